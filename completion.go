@@ -0,0 +1,242 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/bassosimone/must"
+)
+
+const (
+	// completionSubcommandDescr describes the completion subcommand.
+	completionSubcommandDescr = "Print the shell completion script."
+
+	// bashCompletionSubcommandDescr describes the `completion bash` subcommand.
+	bashCompletionSubcommandDescr = "Print the bash completion script."
+
+	// fishCompletionSubcommandDescr describes the `completion fish` subcommand.
+	fishCompletionSubcommandDescr = "Print the fish completion script."
+
+	// zshCompletionSubcommandDescr describes the `completion zsh` subcommand.
+	zshCompletionSubcommandDescr = "Print the zsh completion script."
+)
+
+// AddCompletionHandlers adds the `completion` subcommand, which prints the shell
+// completion script for the whole command tree rooted at this [*DispatcherCommand].
+//
+// For example, `example completion bash` prints the bash completion script. The
+// supported shells are `bash`, `fish`, and `zsh`.
+//
+// The generated scripts do not embed the command tree. Rather, they invoke the
+// hidden `__complete` subcommand, which walks the tree at runtime and asks the
+// [Completer] implemented by the selected command for candidates.
+func (c *DispatcherCommand) AddCompletionHandlers() {
+	disp := NewDispatcherCommand("completion", c.ErrorHandling)
+	disp.AddDescription(completionSubcommandDescr)
+	disp.Exit = c.Exit
+	disp.Stderr = c.Stderr
+	disp.Stdout = c.Stdout
+
+	disp.AddCommand(
+		"bash",
+		CommandFunc(c.completionMainFunc(PrintBashCompletion)),
+		bashCompletionSubcommandDescr,
+		"To load the completion in the current shell, run:",
+		fmt.Sprintf("    source <(%s completion bash)", c.Name),
+	)
+	disp.AddCommand(
+		"fish",
+		CommandFunc(c.completionMainFunc(PrintFishCompletion)),
+		fishCompletionSubcommandDescr,
+		"To load the completion in the current shell, run:",
+		fmt.Sprintf("    %s completion fish | source", c.Name),
+	)
+	disp.AddCommand(
+		"zsh",
+		CommandFunc(c.completionMainFunc(PrintZshCompletion)),
+		zshCompletionSubcommandDescr,
+		"To load the completion in the current shell, run:",
+		fmt.Sprintf("    source <(%s completion zsh)", c.Name),
+	)
+
+	c.addBuiltinCommand("completion", disp, completionSubcommandDescr)
+}
+
+// completionMainFunc returns the function to implement a `completion <shell>` subcommand.
+func (c *DispatcherCommand) completionMainFunc(printer func(*DispatcherCommand, io.Writer)) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		printer(c, c.Stdout)
+		return nil
+	}
+}
+
+// completeSubcommandName is the name of the hidden subcommand used by the
+// completion scripts to obtain the completion candidates.
+const completeSubcommandName = "__complete"
+
+// Completion is a completion candidate.
+type Completion struct {
+	// Value is the word to insert into the command line.
+	Value string
+
+	// Descr is the optional description that some shells show next to Value.
+	Descr string
+}
+
+// Completer is an optional interface that a [Command] may implement to
+// provide dynamic completions for its arguments.
+//
+// The args are the arguments following the command name. The last element
+// of args is the (possibly empty) word being completed.
+//
+// Returning no candidates causes the shell to fall back to its default
+// completion, which typically completes file names.
+type Completer interface {
+	Complete(ctx context.Context, args []string) ([]Completion, error)
+}
+
+// CompleterFunc transforms a func into a [Completer].
+type CompleterFunc func(ctx context.Context, args []string) ([]Completion, error)
+
+var _ Completer = CompleterFunc(nil)
+
+// Complete implements [Completer].
+func (fx CompleterFunc) Complete(ctx context.Context, args []string) ([]Completion, error) {
+	return fx(ctx, args)
+}
+
+var _ Completer = &DispatcherCommand{}
+
+// Complete implements [Completer].
+//
+// When args contains a single word, this method returns the names and aliases of
+// the non-hidden, non-deprecated commands and plugins, the shortcuts, and the user
+// aliases starting with such a word. Otherwise, it expands the first word when it
+// is a shortcut or a user alias and routes the completion to the command named by
+// the first word, provided that it implements [Completer].
+//
+// We load the configuration file, when enabled, to obtain the user aliases, and
+// we ignore the configuration errors such that we can still complete commands.
+func (c *DispatcherCommand) Complete(ctx context.Context, args []string) ([]Completion, error) {
+	if loaded, err := c.maybeLoadConfig(ctx); err == nil {
+		ctx = loaded
+		_ = c.loadUserAliases(ConfigSectionFromContext(ctx))
+	}
+
+	// skip the global flags preceding the command name
+	if len(args) > 1 {
+		leading, rest, _ := c.splitGlobalFlags(args[:len(args)-1])
+		if len(rest) <= 0 && c.missingGlobalFlagValue(leading) {
+			return nil, nil // let the shell complete the flag value
+		}
+		args = append(slices.Clone(rest), args[len(args)-1])
+	}
+
+	// complete the command names and aliases, and the global flags
+	if len(args) <= 1 {
+		var prefix string
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		var completions []Completion
+		for _, flag := range c.GlobalFlags {
+			if word := "--" + flag.Long; flag.Long != "" && strings.HasPrefix(prefix, "-") && strings.HasPrefix(word, prefix) {
+				completions = append(completions, Completion{Value: word, Descr: firstParagraph(flag.Descr)})
+			}
+		}
+		for _, name := range c.childNames() {
+			command, aliases := c.childCommand(name)
+			if command.Hidden || command.Deprecated != nil {
+				continue
+			}
+			descr := firstParagraph(command.Descr)
+			for _, word := range append([]string{name}, aliases...) {
+				if strings.HasPrefix(word, prefix) {
+					completions = append(completions, Completion{Value: word, Descr: descr})
+				}
+			}
+		}
+		for _, name := range c.shortcutNames() {
+			if strings.HasPrefix(name, prefix) && c.shortcutTarget(name).Deprecated == nil {
+				descr := fmt.Sprintf("Shortcut for `%s'.", joinShellWords(c.CommandShortcuts[name]))
+				completions = append(completions, Completion{Value: name, Descr: descr})
+			}
+		}
+		for _, name := range c.userAliasNames() {
+			if strings.HasPrefix(name, prefix) {
+				descr := fmt.Sprintf("Alias for `%s'.", joinShellWords(c.UserAliases[name]))
+				completions = append(completions, Completion{Value: name, Descr: descr})
+			}
+		}
+		return completions, nil
+	}
+
+	// route the completion to the selected command
+	args, err := c.expandAliases(args)
+	if err != nil || len(args) <= 1 {
+		return nil, nil
+	}
+	name, cmd, err := c.lookupCommand(args)
+	if err != nil {
+		return nil, nil
+	}
+	completer, ok := cmd.Cmd.(Completer)
+	if !ok {
+		return nil, nil
+	}
+	return completer.Complete(withConfigSubsection(ctx, name), args[1:])
+}
+
+// completeMain is the main of the hidden `__complete` subcommand.
+//
+// It prints a completion candidate per line. Each line contains the value
+// optionally followed by a tab character and by the description.
+func (c *DispatcherCommand) completeMain(ctx context.Context, args []string) error {
+	if len(args) <= 0 {
+		args = []string{""}
+	}
+	completions, err := c.Complete(ctx, args)
+	if err != nil {
+		return err
+	}
+	sanitize := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	for _, completion := range completions {
+		if completion.Descr != "" {
+			must.Fprintf(c.Stdout, "%s\t%s\n", sanitize.Replace(completion.Value), sanitize.Replace(completion.Descr))
+			continue
+		}
+		must.Fprintf(c.Stdout, "%s\n", sanitize.Replace(completion.Value))
+	}
+	return nil
+}
+
+// firstParagraph returns the first paragraph or an empty string.
+func firstParagraph(paragraphs []string) string {
+	if len(paragraphs) <= 0 {
+		return ""
+	}
+	return strings.TrimSpace(paragraphs[0])
+}
+
+// completionFuncName returns the name of the shell function implementing completion.
+func completionFuncName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	return fmt.Sprintf("_%s_completion", sanitized)
+}
+
+// shellQuote quotes the given string using POSIX shell single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"io"
+
+	"github.com/bassosimone/must"
+)
+
+// PrintBashCompletion prints the bash completion script for the given [*DispatcherCommand].
+//
+// The script obtains the candidates from the hidden `__complete` subcommand and
+// falls back to the default bash completion when there are no candidates.
+//
+// This function panics on I/O error.
+func PrintBashCompletion(c *DispatcherCommand, w io.Writer) {
+	fname := completionFuncName(c.Name)
+	must.Fprintf(w, "# bash completion for %s\n", c.Name)
+	must.Fprintf(w, "\n")
+	must.Fprintf(w, "%s() {\n", fname)
+	must.Fprintf(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	must.Fprintf(w, "    local line\n")
+	must.Fprintf(w, "    COMPREPLY=()\n")
+	must.Fprintf(w, "    while IFS= read -r line; do\n")
+	must.Fprintf(w, "        line=\"${line%%%%$'\\t'*}\"\n")
+	must.Fprintf(w, "        [[ \"${line}\" == \"${cur}\"* ]] && COMPREPLY+=(\"${line}\")\n")
+	must.Fprintf(w, "    done < <(%s %s \"${COMP_WORDS[@]:1:COMP_CWORD}\" 2>/dev/null)\n",
+		shellQuote(c.Name), completeSubcommandName)
+	must.Fprintf(w, "}\n")
+	must.Fprintf(w, "\n")
+	must.Fprintf(w, "complete -o default -F %s %s\n", fname, shellQuote(c.Name))
+}
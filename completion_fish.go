@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"io"
+	"strings"
+
+	"github.com/bassosimone/must"
+)
+
+// PrintFishCompletion prints the fish completion script for the given [*DispatcherCommand].
+//
+// The script obtains the candidates from the hidden `__complete` subcommand,
+// shows their descriptions, and falls back to completing paths when there
+// are no candidates.
+//
+// This function panics on I/O error.
+func PrintFishCompletion(c *DispatcherCommand, w io.Writer) {
+	fname := completionFuncName(c.Name)
+	must.Fprintf(w, "# fish completion for %s\n", c.Name)
+	must.Fprintf(w, "\n")
+	must.Fprintf(w, "function %s\n", fname)
+	must.Fprintf(w, "    set -l tokens (commandline -opc)\n")
+	must.Fprintf(w, "    set -e tokens[1]\n")
+	must.Fprintf(w, "    set -l cur (commandline -ct)\n")
+	must.Fprintf(w, "    set -l candidates (%s %s $tokens \"$cur\" 2>/dev/null)\n",
+		fishQuote(c.Name), completeSubcommandName)
+	must.Fprintf(w, "    if test (count $candidates) -eq 0\n")
+	must.Fprintf(w, "        __fish_complete_path \"$cur\"\n")
+	must.Fprintf(w, "        return\n")
+	must.Fprintf(w, "    end\n")
+	must.Fprintf(w, "    printf '%%s\\n' $candidates\n")
+	must.Fprintf(w, "end\n")
+	must.Fprintf(w, "\n")
+	must.Fprintf(w, "complete -c %s -f -a %s\n", fishQuote(c.Name), fishQuote("("+fname+")"))
+}
+
+// fishQuote quotes the given string using fish single quotes.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
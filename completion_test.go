@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCompleterCommand is a [Command] implementing [Completer] using a [CompleterFunc].
+type testCompleterCommand struct {
+	testCommand
+	CompleterFunc
+}
+
+func TestDispatcherCommandComplete(t *testing.T) {
+	var (
+		curlArgs []string
+		curlErr  error
+	)
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddCommand("curl", &testCompleterCommand{CompleterFunc: func(ctx context.Context, args []string) ([]Completion, error) {
+		curlArgs = args
+		if curlErr != nil {
+			return nil, curlErr
+		}
+		return []Completion{{Value: "https://example.com/"}, {Value: "https://example.org/", Descr: "Example\tsite"}}, nil
+	}}, "Utility to transfer URLs.")
+	net.MustAddCommandAlias("curl", "c")
+
+	var stdout bytes.Buffer
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.Stdout = &stdout
+	disp.AddCommand("net", net, "Network commands.")
+	disp.MustAddCommandAlias("net", "n")
+	disp.AddCommand("dig", &testCommand{}, "Utility to query DNS servers.")
+
+	t.Run("names", func(t *testing.T) {
+		completions, err := disp.Complete(context.Background(), []string{"n"})
+		require.NoError(t, err)
+		assert.Equal(t, []Completion{
+			{Value: "net", Descr: "Network commands."},
+			{Value: "n", Descr: "Network commands."},
+		}, completions)
+
+		completions, err = disp.Complete(context.Background(), []string{})
+		require.NoError(t, err)
+		assert.Len(t, completions, 6)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		completions, err := disp.Complete(context.Background(), []string{"n", "c"})
+		require.NoError(t, err)
+		assert.Equal(t, []Completion{
+			{Value: "curl", Descr: "Utility to transfer URLs."},
+			{Value: "c", Descr: "Utility to transfer URLs."},
+		}, completions)
+	})
+
+	t.Run("leaf completer", func(t *testing.T) {
+		completions, err := disp.Complete(context.Background(), []string{"net", "curl", "-L", "https://"})
+		require.NoError(t, err)
+		assert.Len(t, completions, 2)
+		assert.Equal(t, []string{"-L", "https://"}, curlArgs)
+	})
+
+	t.Run("no candidates", func(t *testing.T) {
+		for _, args := range [][]string{{"dig", ""}, {"nope", ""}, {"net", "nope", ""}} {
+			completions, err := disp.Complete(context.Background(), args)
+			require.NoError(t, err)
+			assert.Empty(t, completions)
+		}
+	})
+
+	t.Run("main protocol", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{completeSubcommandName, "net", "curl", ""})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/\nhttps://example.org/\tExample site\n", stdout.String())
+	})
+
+	t.Run("main without args", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{completeSubcommandName})
+		require.NoError(t, err)
+		assert.Contains(t, stdout.String(), "dig\tUtility to query DNS servers.\n")
+	})
+
+	t.Run("main hidden from help", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{"--help"})
+		require.NoError(t, err)
+		assert.NotContains(t, stdout.String(), completeSubcommandName)
+	})
+
+	t.Run("main error", func(t *testing.T) {
+		curlErr = errors.New("boom")
+		err := disp.Main(context.Background(), []string{completeSubcommandName, "net", "curl", ""})
+		assert.ErrorIs(t, err, curlErr)
+	})
+}
+
+func TestDispatcherCommandCompletionScripts(t *testing.T) {
+	testCases := []struct {
+		shell   string
+		expects []string
+	}{{
+		shell: "bash",
+		expects: []string{
+			"_example_completion() {\n",
+			"done < <('example' __complete \"${COMP_WORDS[@]:1:COMP_CWORD}\" 2>/dev/null)\n",
+			"complete -o default -F _example_completion 'example'\n",
+		},
+	}, {
+		shell: "fish",
+		expects: []string{
+			"function _example_completion\n",
+			"set -l candidates ('example' __complete $tokens \"$cur\" 2>/dev/null)\n",
+			"complete -c 'example' -f -a '(_example_completion)'\n",
+		},
+	}, {
+		shell: "zsh",
+		expects: []string{
+			"#compdef example\n",
+			"for line in \"${(@f)$('example' __complete \"${(@)words[2,CURRENT]}\" 2>/dev/null)}\"; do\n",
+			"compdef _example_completion 'example'\n",
+		},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.shell, func(t *testing.T) {
+			disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+			disp.AddCompletionHandlers()
+			var stdout bytes.Buffer
+			disp.Stdout = &stdout
+
+			err := disp.Main(context.Background(), []string{"completion", tc.shell})
+			require.NoError(t, err)
+			for _, expect := range tc.expects {
+				assert.Contains(t, stdout.String(), expect)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `''`, shellQuote(""))
+	assert.Equal(t, `'curl'`, shellQuote("curl"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestFishQuote(t *testing.T) {
+	assert.Equal(t, `''`, fishQuote(""))
+	assert.Equal(t, `'curl'`, fishQuote("curl"))
+	assert.Equal(t, `'it\'s a \\ test'`, fishQuote(`it's a \ test`))
+}
+
+func TestDispatcherCommandCompletionUsage(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	var stdout bytes.Buffer
+	disp.Stdout = &stdout
+	disp.AddCompletionHandlers()
+
+	err := disp.Main(context.Background(), []string{"completion", "--help"})
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "\n    example completion <command> [args...]\n")
+}
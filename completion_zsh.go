@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"io"
+
+	"github.com/bassosimone/must"
+)
+
+// PrintZshCompletion prints the zsh completion script for the given [*DispatcherCommand].
+//
+// The script obtains the candidates from the hidden `__complete` subcommand,
+// shows their descriptions, and falls back to completing file names when
+// there are no candidates.
+//
+// This function panics on I/O error.
+func PrintZshCompletion(c *DispatcherCommand, w io.Writer) {
+	fname := completionFuncName(c.Name)
+	must.Fprintf(w, "#compdef %s\n", c.Name)
+	must.Fprintf(w, "\n")
+	must.Fprintf(w, "# zsh completion for %s\n", c.Name)
+	must.Fprintf(w, "\n")
+	must.Fprintf(w, "%s() {\n", fname)
+	must.Fprintf(w, "    local -a candidates\n")
+	must.Fprintf(w, "    local line word\n")
+	must.Fprintf(w, "    for line in \"${(@f)$(%s %s \"${(@)words[2,CURRENT]}\" 2>/dev/null)}\"; do\n",
+		shellQuote(c.Name), completeSubcommandName)
+	must.Fprintf(w, "        [[ -z \"${line}\" ]] && continue\n")
+	must.Fprintf(w, "        word=\"${${line%%%%$'\\t'*}//:/\\\\:}\"\n")
+	must.Fprintf(w, "        if [[ \"${line}\" == *$'\\t'* ]]; then\n")
+	must.Fprintf(w, "            candidates+=(\"${word}:${line#*$'\\t'}\")\n")
+	must.Fprintf(w, "        else\n")
+	must.Fprintf(w, "            candidates+=(\"${word}\")\n")
+	must.Fprintf(w, "        fi\n")
+	must.Fprintf(w, "    done\n")
+	must.Fprintf(w, "    if (( ${#candidates} )); then\n")
+	must.Fprintf(w, "        _describe -t values 'candidate' candidates\n")
+	must.Fprintf(w, "    else\n")
+	must.Fprintf(w, "        _files\n")
+	must.Fprintf(w, "    fi\n")
+	must.Fprintf(w, "}\n")
+	must.Fprintf(w, "\n")
+	must.Fprintf(w, "compdef %s %s\n", fname, shellQuote(c.Name))
+}
@@ -0,0 +1,290 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ConfigDecoder decodes the content of a configuration file.
+//
+// The return value maps keys to values, which are either scalars, slices, or nested
+// maps, as returned by [json.Unmarshal] when decoding into a map[string]any.
+type ConfigDecoder func(data []byte) (map[string]any, error)
+
+// DecodeJSONConfig is the [ConfigDecoder] for JSON configuration files.
+func DecodeJSONConfig(data []byte) (map[string]any, error) {
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, errors.New("expected a JSON object")
+	}
+	return values, nil
+}
+
+// DecodeYAMLConfig is the [ConfigDecoder] for YAML configuration files.
+//
+// Like JSON object keys, mapping keys are always strings (e.g., `1: a` has the `1` key).
+func DecodeYAMLConfig(data []byte) (map[string]any, error) {
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, errors.New("expected a YAML mapping")
+	}
+	return normalizeYAMLValue(values).(map[string]any), nil
+}
+
+// normalizeYAMLValue recursively converts the mappings with non-string keys,
+// which yaml.v3 decodes as map[any]any, to map[string]any, such that
+// [*ConfigSection] handles them like the other mappings.
+func normalizeYAMLValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, elem := range value {
+			value[key] = normalizeYAMLValue(elem)
+		}
+		return value
+	case map[any]any:
+		converted := make(map[string]any, len(value))
+		for key, elem := range value {
+			converted[fmt.Sprint(key)] = normalizeYAMLValue(elem)
+		}
+		return converted
+	case []any:
+		for idx, elem := range value {
+			value[idx] = normalizeYAMLValue(elem)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// ErrUnsupportedConfigFormat indicates that there is no [ConfigDecoder]
+// for the extension of the configuration file.
+var ErrUnsupportedConfigFormat = errors.New("unsupported config file format")
+
+const (
+	// configFlagName is the long name of the global flag selecting the config file.
+	configFlagName = "config"
+
+	// configEnvVarName is the name of the environment variable selecting the config file.
+	configEnvVarName = "CONFIG"
+)
+
+// EnableConfig enables loading a configuration file shared by all the
+// commands reachable from the [*DispatcherCommand]. We use the first
+// configuration file found using the following sources:
+//
+//  1. the `--config FILE` global flag (we reuse an already declared
+//     global flag named `config`, if any);
+//
+//  2. the environment variable named after the command path (e.g.,
+//     `EXAMPLE_CONFIG`);
+//
+//  3. the `config.<ext>` file inside `$XDG_CONFIG_HOME/<program>` (or inside
+//     `$HOME/.config/<program>` when XDG_CONFIG_HOME is not set), where
+//     `<program>` is the first element of the Path and we try `json` first,
+//     followed by the other extensions in ConfigDecoders.
+//
+// It is an error if the file selected using the first two sources does not
+// exist. Conversely, it is fine not to have a file in the XDG directory.
+//
+// We select the [ConfigDecoder] using the file extension. JSON (`json`) and YAML
+// (`yaml` and `yml`) are supported out of the box: to support other formats
+// (e.g., TOML), add decoders to ConfigDecoders.
+//
+// The configuration file contains a section for each command, where nested
+// objects named after subcommands contain the subcommand sections:
+//
+//	{
+//	  "timeout": "10s",
+//	  "net": {
+//	    "curl": {
+//	      "timeout": "5s"
+//	    }
+//	  }
+//	}
+//
+// Commands access their section using [ConfigSectionFromContext]. The `aliases` object
+// within the section of a [*DispatcherCommand] defines its UserAliases.
+func (c *DispatcherCommand) EnableConfig() {
+	if !slices.ContainsFunc(c.GlobalFlags, func(flag GlobalFlag) bool { return flag.Long == configFlagName }) {
+		c.AddGlobalStringFlag(0, configFlagName, "FILE", fmt.Sprintf(
+			"Read the configuration from FILE instead of `$XDG_CONFIG_HOME/%s/config.json'.", c.programName()))
+	}
+	c.AddEnvVar(configEnvVarName, "Path of the configuration file to use when not using `--config'.")
+	c.configEnabled = true
+}
+
+// configFileName returns the name of the configuration file to load, if any.
+func (c *DispatcherCommand) configFileName(ctx context.Context) (string, bool) {
+	if value, found := GlobalFlagValuesFromContext(ctx).Lookup(configFlagName); found {
+		return value, true
+	}
+	if value, found := EnvironmentFromContext(ctx).Lookup(configEnvVarName); found {
+		return value, true
+	}
+	dir, found := c.LookupEnv("XDG_CONFIG_HOME")
+	if !found || dir == "" {
+		home, found := c.LookupEnv("HOME")
+		if !found || home == "" {
+			return "", false
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	for _, ext := range c.configExtensions() {
+		name := filepath.Join(dir, c.programName(), "config."+ext)
+		if _, err := os.Stat(name); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// programName returns the name of the program, which is the first element of the
+// path, such that nested commands share the configuration directory.
+func (c *DispatcherCommand) programName() string {
+	if path := c.fullPath(); len(path) > 0 {
+		return path[0]
+	}
+	return c.Name
+}
+
+// configExtensions returns the extensions of ConfigDecoders with `json` first.
+func (c *DispatcherCommand) configExtensions() []string {
+	return slices.SortedFunc(maps.Keys(c.ConfigDecoders), func(a, b string) int {
+		switch {
+		case a == b:
+			return 0
+		case a == "json":
+			return -1
+		case b == "json":
+			return 1
+		default:
+			return strings.Compare(a, b)
+		}
+	})
+}
+
+// maybeLoadConfig loads the configuration file when we have enabled loading it and
+// an outer [*DispatcherCommand] did not already load it. The returned context
+// contains the [*ConfigSection] of the [*DispatcherCommand].
+func (c *DispatcherCommand) maybeLoadConfig(ctx context.Context) (context.Context, error) {
+	if !c.configEnabled {
+		return ctx, nil
+	}
+	if _, found := ctx.Value(configSectionKey{}).(*ConfigSection); found {
+		return ctx, nil
+	}
+	section := &ConfigSection{fileName: "", values: map[string]any{}}
+	if name, found := c.configFileName(ctx); found {
+		values, err := c.loadConfig(name)
+		if err != nil {
+			return ctx, fmt.Errorf("%s: %w", c.FullName(), err)
+		}
+		section = &ConfigSection{fileName: name, values: values}
+	}
+	return context.WithValue(ctx, configSectionKey{}, section), nil
+}
+
+// loadConfig reads and decodes the given configuration file.
+func (c *DispatcherCommand) loadConfig(name string) (map[string]any, error) {
+	decoder, found := c.ConfigDecoders[strings.TrimPrefix(filepath.Ext(name), ".")]
+	if !found {
+		return nil, fmt.Errorf("%s: %w", name, ErrUnsupportedConfigFormat)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	values, err := decoder(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return values, nil
+}
+
+// withConfigSubsection returns a context containing the section of the
+// given command, when the context contains a [*ConfigSection].
+func withConfigSubsection(ctx context.Context, name string) context.Context {
+	section, found := ctx.Value(configSectionKey{}).(*ConfigSection)
+	if !found {
+		return ctx
+	}
+	return context.WithValue(ctx, configSectionKey{}, section.Section(name))
+}
+
+// configSectionKey is the context key for [*ConfigSection].
+type configSectionKey struct{}
+
+// ConfigSection is the section of the configuration file for a command.
+//
+// Obtain using [ConfigSectionFromContext].
+type ConfigSection struct {
+	// fileName is the name of the configuration file.
+	fileName string
+
+	// values contains the section values.
+	values map[string]any
+}
+
+// ConfigSectionFromContext returns the [*ConfigSection] stored in the context.
+//
+// The return value is never nil, yet it is empty when there is no configuration file.
+func ConfigSectionFromContext(ctx context.Context) *ConfigSection {
+	if section, found := ctx.Value(configSectionKey{}).(*ConfigSection); found {
+		return section
+	}
+	return &ConfigSection{fileName: "", values: map[string]any{}}
+}
+
+// FileName returns the name of the configuration file or an empty
+// string when we have not loaded any configuration file.
+func (cs *ConfigSection) FileName() string {
+	return cs.fileName
+}
+
+// Lookup returns the value of the given key and whether the key exists.
+func (cs *ConfigSection) Lookup(key string) (any, bool) {
+	value, found := cs.values[key]
+	return value, found
+}
+
+// Section returns the nested section with the given name, which
+// is empty when the section does not exist or is not an object.
+func (cs *ConfigSection) Section(name string) *ConfigSection {
+	values, _ := cs.values[name].(map[string]any)
+	if values == nil {
+		values = map[string]any{}
+	}
+	return &ConfigSection{fileName: cs.fileName, values: values}
+}
+
+// Decode decodes the section into the given value using the same
+// rules of [json.Unmarshal] (e.g., honoring `json` struct tags).
+func (cs *ConfigSection) Decode(v any) error {
+	data, err := json.Marshal(cs.values)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil && cs.fileName != "" {
+		err = fmt.Errorf("%s: %w", cs.fileName, err)
+	}
+	return err
+}
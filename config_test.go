@@ -0,0 +1,290 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigJSON = `{
+  "timeout": "10s",
+  "net": {
+    "proxy": "http://127.0.0.1:8080/",
+    "curl": {"timeout": "5s", "retries": 3}
+  }
+}`
+
+func writeTestConfig(t *testing.T, name, content string) string {
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+	require.NoError(t, os.WriteFile(name, []byte(content), 0644))
+	return name
+}
+
+func TestDispatcherCommandConfig(t *testing.T) {
+	var (
+		section *ConfigSection
+		vars    map[string]string
+	)
+	saveSection := CommandFunc(func(ctx context.Context, args []string) error {
+		section = ConfigSectionFromContext(ctx)
+		return nil
+	})
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddCommand("curl", saveSection, "Utility to transfer URLs.")
+
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.LookupEnv = func(key string) (string, bool) {
+		value, found := vars[key]
+		return value, found
+	}
+	disp.AddCommand("net", net, "Network commands.")
+	disp.AddCommand("dig", saveSection, "Utility to query DNS servers.")
+	disp.EnableConfig()
+
+	// run runs the dispatcher with the given environment variables and arguments
+	run := func(env map[string]string, args ...string) error {
+		section, vars = nil, env
+		return disp.Main(context.Background(), args)
+	}
+
+	dir := t.TempDir()
+	xdgConfig := writeTestConfig(t, filepath.Join(dir, "xdg", "example", "config.json"), testConfigJSON)
+	homeConfig := writeTestConfig(t, filepath.Join(dir, "home", ".config", "example", "config.json"), testConfigJSON)
+	envConfig := writeTestConfig(t, filepath.Join(dir, "env.json"), testConfigJSON)
+	flagConfig := writeTestConfig(t, filepath.Join(dir, "flag.json"), testConfigJSON)
+
+	sourceCases := []struct {
+		name   string
+		args   []string
+		vars   map[string]string
+		expect string
+	}{{
+		name:   "no config",
+		args:   []string{"net", "curl"},
+		vars:   map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "nonexistent")},
+		expect: "",
+	}, {
+		name:   "no config directory",
+		args:   []string{"net", "curl"},
+		vars:   map[string]string{},
+		expect: "",
+	}, {
+		name:   "XDG",
+		args:   []string{"net", "curl"},
+		vars:   map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "xdg"), "HOME": filepath.Join(dir, "home")},
+		expect: xdgConfig,
+	}, {
+		name:   "home",
+		args:   []string{"net", "curl"},
+		vars:   map[string]string{"HOME": filepath.Join(dir, "home")},
+		expect: homeConfig,
+	}, {
+		name:   "environment",
+		args:   []string{"net", "curl"},
+		vars:   map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "xdg"), "EXAMPLE_CONFIG": envConfig},
+		expect: envConfig,
+	}, {
+		name:   "flag",
+		args:   []string{"--config", flagConfig, "net", "curl"},
+		vars:   map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "xdg"), "EXAMPLE_CONFIG": envConfig},
+		expect: flagConfig,
+	}}
+	for _, tc := range sourceCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, run(tc.vars, tc.args...))
+			assert.Equal(t, tc.expect, section.FileName())
+		})
+	}
+
+	t.Run("nested command", func(t *testing.T) {
+		require.NoError(t, run(map[string]string{"EXAMPLE_CONFIG": envConfig}, "net", "curl"))
+
+		var config struct {
+			Retries int    `json:"retries"`
+			Timeout string `json:"timeout"`
+		}
+		require.NoError(t, section.Decode(&config))
+		assert.Equal(t, 3, config.Retries)
+		assert.Equal(t, "5s", config.Timeout)
+
+		value, found := section.Lookup("timeout")
+		assert.True(t, found)
+		assert.Equal(t, "5s", value)
+		_, found = section.Lookup("proxy")
+		assert.False(t, found)
+	})
+
+	t.Run("command without section", func(t *testing.T) {
+		require.NoError(t, run(map[string]string{"EXAMPLE_CONFIG": envConfig}, "dig"))
+		assert.Equal(t, envConfig, section.FileName())
+		_, found := section.Lookup("timeout")
+		assert.False(t, found)
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		require.NoError(t, run(map[string]string{"EXAMPLE_CONFIG": envConfig}, "net", "curl"))
+
+		var config struct {
+			Retries string `json:"retries"`
+		}
+		err := section.Decode(&config)
+		assert.ErrorContains(t, err, envConfig+": ")
+	})
+
+	invalid := writeTestConfig(t, filepath.Join(dir, "invalid.json"), `{`)
+	notObject := writeTestConfig(t, filepath.Join(dir, "null.json"), `null`)
+	unsupported := writeTestConfig(t, filepath.Join(dir, "config.toml"), `timeout = "5s"`)
+
+	errorCases := []struct {
+		name   string
+		file   string
+		expect string
+	}{{
+		name:   "missing",
+		file:   filepath.Join(dir, "nonexistent.json"),
+		expect: "example: open " + filepath.Join(dir, "nonexistent.json"),
+	}, {
+		name:   "invalid",
+		file:   invalid,
+		expect: "example: " + invalid + ": unexpected end of JSON input",
+	}, {
+		name:   "not an object",
+		file:   notObject,
+		expect: "example: " + notObject + ": expected a JSON object",
+	}, {
+		name:   "unsupported",
+		file:   unsupported,
+		expect: "example: " + unsupported + ": unsupported config file format",
+	}}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := run(nil, "--config", tc.file, "net", "curl")
+			require.Error(t, err)
+			assert.True(t, strings.HasPrefix(err.Error(), tc.expect), err.Error())
+			assert.Nil(t, section)
+		})
+	}
+
+	t.Run("unsupported format is ErrUnsupportedConfigFormat", func(t *testing.T) {
+		err := run(map[string]string{"EXAMPLE_CONFIG": unsupported}, "dig")
+		assert.ErrorIs(t, err, ErrUnsupportedConfigFormat)
+	})
+
+	for _, ext := range []string{"yaml", "yml"} {
+		t.Run(ext, func(t *testing.T) {
+			const content = "timeout: 10s\nnet:\n  curl:\n    retries: 3\n"
+			name := writeTestConfig(t, filepath.Join(dir, ext, "example", "config."+ext), content)
+
+			require.NoError(t, run(map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, ext)}, "net", "curl"))
+			assert.Equal(t, name, section.FileName())
+			var config struct {
+				Retries int `json:"retries"`
+			}
+			require.NoError(t, section.Decode(&config))
+			assert.Equal(t, 3, config.Retries)
+		})
+	}
+
+	t.Run("custom decoder", func(t *testing.T) {
+		disp.ConfigDecoders["kv"] = func(data []byte) (map[string]any, error) {
+			values := map[string]any{}
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				key, value, _ := strings.Cut(line, "=")
+				values[key] = value
+			}
+			return values, nil
+		}
+
+		kvConfig := writeTestConfig(t, filepath.Join(dir, "kv", "example", "config.kv"), "timeout=5s\n")
+		require.NoError(t, run(map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "kv")}, "dig"))
+		assert.Equal(t, kvConfig, section.FileName())
+
+		// the JSON file wins over the one using the custom decoder
+		writeTestConfig(t, filepath.Join(dir, "kv", "example", "config.json"), "{}")
+		require.NoError(t, run(map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "kv")}, "dig"))
+		assert.Equal(t, filepath.Join(dir, "kv", "example", "config.json"), section.FileName())
+	})
+
+	t.Run("does not prevent help", func(t *testing.T) {
+		var stdout bytes.Buffer
+		disp.Stdout = &stdout
+		require.NoError(t, run(map[string]string{"EXAMPLE_CONFIG": "/nonexistent.json"}, "--help"))
+		assert.Contains(t, stdout.String(), "\n    --config FILE\n")
+		assert.Contains(t, stdout.String(), "\n    EXAMPLE_CONFIG\n")
+	})
+}
+
+func TestConfigSectionSectionThatIsNotAnObject(t *testing.T) {
+	section := (&ConfigSection{values: map[string]any{"net": 1}}).Section("net")
+	_, found := section.Lookup("curl")
+	assert.False(t, found)
+}
+
+func TestDecodeYAMLConfig(t *testing.T) {
+	t.Run("non-string keys", func(t *testing.T) {
+		values, err := DecodeYAMLConfig([]byte("net:\n  curl:\n    1: a\n    retries: 3\n    hosts: [{true: x}]\n"))
+		require.NoError(t, err)
+		section := (&ConfigSection{values: values}).Section("net").Section("curl")
+		var config struct {
+			One     string           `json:"1"`
+			Retries int              `json:"retries"`
+			Hosts   []map[string]any `json:"hosts"`
+		}
+		require.NoError(t, section.Decode(&config))
+		assert.Equal(t, "a", config.One)
+		assert.Equal(t, 3, config.Retries)
+		assert.Equal(t, []map[string]any{{"true": "x"}}, config.Hosts)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := DecodeYAMLConfig([]byte("timeout: [\n"))
+		assert.Error(t, err)
+		_, err = DecodeYAMLConfig([]byte(""))
+		assert.EqualError(t, err, "expected a YAML mapping")
+	})
+}
+
+func TestDispatcherCommandConfigNestedUsesProgramDirectory(t *testing.T) {
+	dir := t.TempDir()
+	name := writeTestConfig(t, filepath.Join(dir, "example", "config.json"), testConfigJSON)
+	var section *ConfigSection
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.LookupEnv = func(key string) (string, bool) {
+		return dir, key == "XDG_CONFIG_HOME"
+	}
+	net.AddCommand("curl", CommandFunc(func(ctx context.Context, args []string) error {
+		section = ConfigSectionFromContext(ctx)
+		return nil
+	}))
+	net.EnableConfig()
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("net", net)
+
+	require.NoError(t, disp.Main(context.Background(), []string{"net", "curl"}))
+	assert.Equal(t, name, section.FileName())
+}
+
+func TestDispatcherCommandEnableConfigReusesConfigFlag(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddGlobalStringFlag('c', "config", "FILE", "Custom description.")
+	disp.EnableConfig()
+	assert.Len(t, disp.GlobalFlags, 1)
+	assert.Equal(t, []string{"Custom description."}, disp.GlobalFlags[0].Descr)
+}
+
+func TestConfigSectionFromContextEmpty(t *testing.T) {
+	section := ConfigSectionFromContext(context.Background())
+	assert.Equal(t, "", section.FileName())
+	var config map[string]any
+	require.NoError(t, section.Decode(&config))
+	assert.Empty(t, config)
+}
@@ -2,15 +2,34 @@
 
 package vclip
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 // DescribedCommand is a command living side by side with its docs.
 type DescribedCommand struct {
 	// Cmd is the [Command].
 	Cmd Command
 
+	// Deprecated, when not nil, indicates that the [Command] is deprecated.
+	Deprecated *Deprecation
+
 	// Descr contains the [Command] description.
 	Descr []string
+
+	// EnvVars contains the environment variables applying to the [Command].
+	EnvVars []EnvVar
+
+	// Group is the optional name of the group the [Command] belongs to.
+	Group string
+
+	// Hidden indicates that the [Command] is still dispatchable but should
+	// not appear in the help, in the completions, and in the generated docs.
+	Hidden bool
+
+	// Weight is the weight used to sort commands with [CommandOrderWeight].
+	Weight int
 }
 
 // NewDescribedCommand creates a [Command] along with the related documentation.
@@ -27,3 +46,25 @@ var _ Command = DescribedCommand{}
 func (dc DescribedCommand) Main(ctx context.Context, args []string) error {
 	return dc.Cmd.Main(ctx, args)
 }
+
+// Deprecation contains information about a deprecated [Command].
+type Deprecation struct {
+	// RemovalVersion is the optional version that will remove the [Command].
+	RemovalVersion string `json:"removal_version,omitempty"`
+
+	// Replacement is the optional name of the [Command] to use instead.
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// details returns the deprecation details to append to a message
+// saying that a command is deprecated or an empty string.
+func (d *Deprecation) details() string {
+	var details string
+	if d.RemovalVersion != "" {
+		details += fmt.Sprintf(" and will be removed in %s", d.RemovalVersion)
+	}
+	if d.Replacement != "" {
+		details += fmt.Sprintf("; use `%s' instead", d.Replacement)
+	}
+	return details
+}
@@ -7,7 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/bassosimone/must"
 	"github.com/bassosimone/runtimex"
@@ -18,16 +22,43 @@ import (
 //
 // Construct using [NewDispatcherCommand].
 type DispatcherCommand struct {
+	// AllowPrefixMatching enables selecting a command using an unambiguous
+	// prefix of its name or aliases (e.g., `cu` for `curl`).
+	//
+	// When the prefix is ambiguous, we fail with an [*AmbiguousCommandError].
+	//
+	// [NewDispatcherCommand] initializes it to false.
+	AllowPrefixMatching bool
+
 	// CommandAliasToName maps a command alias to its real name.
 	//
 	// [NewDispatcherCommand] initializes it as an empty map.
 	CommandAliasToName map[string]string
 
+	// CommandGroups contains the names of the command groups in the order
+	// in which the help should list them.
+	//
+	// [NewDispatcherCommand] initializes it as an empty slice.
+	CommandGroups []string
+
 	// CommandNameToAliases maps a command name to its aliases.
 	//
 	// [NewDispatcherCommand] initializes it as an empty map.
 	CommandNameToAliases map[string][]string
 
+	// CommandOrder is the [CommandOrder] policy for sorting commands in the
+	// help, in the completions, and in the generated documentation.
+	//
+	// [NewDispatcherCommand] initializes it to [CommandOrderAlphabetical].
+	CommandOrder CommandOrder
+
+	// CommandShortcuts maps the name of a shortcut to its expansion, that is, a
+	// command name followed by the arguments to prepend to the arguments following
+	// the shortcut. See [*DispatcherCommand.MustAddCommandShortcut].
+	//
+	// [NewDispatcherCommand] initializes it as an empty map.
+	CommandShortcuts map[string][]string
+
 	// Commands maps between names and [Command] instances.
 	//
 	// [NewDispatcherCommand] initializes the following built-in subcommands:
@@ -37,11 +68,24 @@ type DispatcherCommand struct {
 	//  2. `--version` and `version` to print the version number.
 	Commands map[string]DescribedCommand
 
+	// ConfigDecoders maps the extension of a configuration file (e.g., `json`)
+	// to the [ConfigDecoder] to use. See [*DispatcherCommand.EnableConfig].
+	//
+	// [NewDispatcherCommand] initializes it to map `json` to [DecodeJSONConfig]
+	// and both `yaml` and `yml` to [DecodeYAMLConfig].
+	ConfigDecoders map[string]ConfigDecoder
+
 	// Description contains the description paragraphs.
 	//
 	// Set to the parameter passed to [NewDispatcherCommand].
 	Description []string
 
+	// EnvVars contains the environment variables applying to the [*DispatcherCommand]
+	// and to all the commands reachable from it. See [*DispatcherCommand.AddEnvVar].
+	//
+	// [NewDispatcherCommand] initializes it as an empty slice.
+	EnvVars []EnvVar
+
 	// ErrorHandling is the [vflag.ErrorHandling] policy to use.
 	//
 	// [NewDispatcherCommand] initializes this field using the corresponding parameter.
@@ -52,6 +96,25 @@ type DispatcherCommand struct {
 	// [NewDispatcherCommand] initializes it to [os.Exit].
 	Exit func(status int)
 
+	// GlobalFlags contains the opt-in global flags, which must appear before
+	// the command name. See [*DispatcherCommand.AddGlobalBoolFlag].
+	//
+	// [NewDispatcherCommand] initializes it as an empty slice.
+	GlobalFlags []GlobalFlag
+
+	// LookupEnv is the function to lookup environment variables.
+	//
+	// When nesting, we use the function of the outermost [*DispatcherCommand].
+	//
+	// [NewDispatcherCommand] initializes it to [os.LookupEnv].
+	LookupEnv func(key string) (string, bool)
+
+	// Middlewares contains the middlewares wrapping the dispatched
+	// commands. See [*DispatcherCommand.Use].
+	//
+	// [NewDispatcherCommand] initializes it as an empty slice.
+	Middlewares []Middleware
+
 	// Name is the command name.
 	//
 	// Set to the parameter passed to [NewDispatcherCommand].
@@ -64,6 +127,30 @@ type DispatcherCommand struct {
 	// that includes a minimal command description.
 	NewHelpSubcommandUsagePrinter func() vflag.UsagePrinter
 
+	// Path contains the full command path (e.g., `example net` for the `net`
+	// command nested under `example`), which we use in usage lines, error
+	// messages, environment variables, and plugin names.
+	//
+	// [NewDispatcherCommand] initializes it to the whitespace-separated words of
+	// the name, such that a command named `example net` has the `example net` path
+	// even when it is not nested. Then, AddCommand sets it to the path of the parent
+	// followed by the name under which we register the [*DispatcherCommand], which
+	// is the name used by [Walk]. Set it explicitly when adding the command
+	// directly to the Commands map of another [*DispatcherCommand].
+	Path []string
+
+	// PluginDescribeTimeout is the maximum time for plugins to describe
+	// themselves. See [PluginDescribeFlag].
+	//
+	// [NewDispatcherCommand] initializes it to two seconds.
+	PluginDescribeTimeout time.Duration
+
+	// PluginDirs contains the directories where to search plugins before
+	// searching the PATH. See [*DispatcherCommand.EnablePlugins].
+	//
+	// [NewDispatcherCommand] initializes it as an empty slice.
+	PluginDirs []string
+
 	// Stderr is the [io.Writer] to use as the stderr.
 	//
 	// [NewDispatcherCommand] initializes this field to [os.Stderr].
@@ -71,6 +158,13 @@ type DispatcherCommand struct {
 	// We use this field with [ExitOnError] policy.
 	Stderr io.Writer
 
+	// Stdin is the [io.Reader] to use as the stdin.
+	//
+	// [NewDispatcherCommand] initializes this field to [os.Stdin].
+	//
+	// We use this field when running plugins.
+	Stdin io.Reader
+
 	// Stdout is the [io.Writer] to use as the stdout.
 	//
 	// [NewDispatcherCommand] initializes this field to [os.Stdout].
@@ -78,10 +172,43 @@ type DispatcherCommand struct {
 	// We use this field with [ExitOnError] policy.
 	Stdout io.Writer
 
+	// UserAliases maps the names of the user aliases to their expansion, that
+	// is, a command name followed by the arguments to prepend to the arguments
+	// following the alias (e.g., `dns` to `dig +short @1.1.1.1`).
+	//
+	// User aliases never shadow registered commands, aliases, and shortcuts,
+	// while they shadow plugins. An expansion may start with another user alias.
+	//
+	// [NewDispatcherCommand] initializes it as an empty map. Then, Main adds
+	// the user aliases from the configuration file (see [*DispatcherCommand.EnableConfig])
+	// contained in the `aliases` object of the section of the [*DispatcherCommand].
+	UserAliases map[string][]string
+
 	// UsagePrinter is the [UsagePrinter] to use.
 	//
 	// Initialized by [NewDispatcherCommand] using [NewDefaultUsagePrinter].
 	UsagePrinter UsagePrinter
+
+	// builtinCommands contains the names of the built-in commands.
+	builtinCommands []string
+
+	// configEnabled indicates that we should load the configuration file.
+	configEnabled bool
+
+	// pluginCache is the persistent cache of the plugin descriptions by path.
+	pluginCache map[string]pluginCacheEntry
+
+	// pluginDescriptions caches the plugin descriptions by path.
+	pluginDescriptions map[string]PluginDescription
+
+	// pluginPaths caches the paths of the plugin executables by name.
+	pluginPaths map[string]string
+
+	// pluginsEnabled indicates that we should dispatch to plugins.
+	pluginsEnabled bool
+
+	// registrationOrder contains the command names in registration order.
+	registrationOrder []string
 }
 
 const (
@@ -93,29 +220,50 @@ const (
 
 	// helpSubcommandDescr describes the help subcommand and the --help and -h flags.
 	helpSubcommandDescr = "Show help about this command or about a subcommand."
+
+	// helpJSONDescr describes the --json flag of the help subcommand.
+	helpJSONDescr = "Use `--json' to print the command tree, or the subtree of the given " +
+		"subcommand, in a machine-readable JSON format."
 )
 
 // NewDispatcherCommand creates a new instance of [*DispatcherCommand].
 func NewDispatcherCommand(name string, handling vflag.ErrorHandling) *DispatcherCommand {
 	c := &DispatcherCommand{
 		CommandAliasToName:   map[string]string{},
+		CommandGroups:        []string{},
 		CommandNameToAliases: map[string][]string{},
+		CommandOrder:         CommandOrderAlphabetical,
+		CommandShortcuts:     map[string][]string{},
 		Commands:             map[string]DescribedCommand{},
-		Description:          []string{},
-		ErrorHandling:        handling,
-		Exit:                 os.Exit,
-		Name:                 name,
+		ConfigDecoders: map[string]ConfigDecoder{
+			"json": DecodeJSONConfig,
+			"yaml": DecodeYAMLConfig,
+			"yml":  DecodeYAMLConfig,
+		},
+		Description:   []string{},
+		EnvVars:       []EnvVar{},
+		ErrorHandling: handling,
+		Exit:          os.Exit,
+		GlobalFlags:   []GlobalFlag{},
+		LookupEnv:     os.LookupEnv,
+		Middlewares:   []Middleware{},
+		Name:          name,
 		NewHelpSubcommandUsagePrinter: func() vflag.UsagePrinter {
 			usage := vflag.NewDefaultUsagePrinter()
-			usage.AddDescription(helpSubcommandDescr)
+			usage.AddDescription(helpSubcommandDescr, helpJSONDescr)
 			return usage
 		},
-		Stdout:       os.Stdout,
-		Stderr:       os.Stderr,
-		UsagePrinter: NewDefaultUsagePrinter(),
+		Path:                  strings.Fields(name),
+		PluginDescribeTimeout: 2 * time.Second,
+		PluginDirs:            []string{},
+		Stdin:                 os.Stdin,
+		Stdout:                os.Stdout,
+		Stderr:                os.Stderr,
+		UserAliases:           map[string][]string{},
+		UsagePrinter:          NewDefaultUsagePrinter(),
 	}
 
-	c.AddCommand("help", CommandFunc(c.helpMain), helpSubcommandDescr)
+	c.addBuiltinCommand("help", CommandFunc(c.helpMain), helpSubcommandDescr)
 	c.MustAddCommandAlias("help", "-h")
 	c.MustAddCommandAlias("help", "--help")
 
@@ -125,7 +273,7 @@ func NewDispatcherCommand(name string, handling vflag.ErrorHandling) *Dispatcher
 // AddVersionHandlers adds code to handle `version` and `--version` by
 // printing the version number passed to this method.
 func (c *DispatcherCommand) AddVersionHandlers(version string) {
-	c.AddCommand("version", CommandFunc(c.versionMainFunc(version)), versionSubcommandDescr)
+	c.addBuiltinCommand("version", CommandFunc(c.versionMainFunc(version)), versionSubcommandDescr)
 	c.MustAddCommandAlias("version", "--version")
 }
 
@@ -139,7 +287,50 @@ func (c *DispatcherCommand) AddDescription(text ...string) {
 // Commands MUST handle the `--help` flag and provide help when they see it regardless
 // of the otherwise different convention they use for flags.
 func (c *DispatcherCommand) AddCommand(name string, cmd Command, descr ...string) {
+	if !slices.Contains(c.registrationOrder, name) {
+		c.registrationOrder = append(c.registrationOrder, name)
+	}
 	c.Commands[name] = NewDescribedCommand(cmd, descr...)
+	c.builtinCommands = slices.DeleteFunc(c.builtinCommands, func(builtin string) bool { return builtin == name })
+	if child, ok := cmd.(*DispatcherCommand); ok {
+		child.setPath(append(c.fullPath(), name))
+	}
+}
+
+// addBuiltinCommand is like AddCommand but marks the command as built-in.
+func (c *DispatcherCommand) addBuiltinCommand(name string, cmd Command, descr ...string) {
+	c.AddCommand(name, cmd, descr...)
+	c.builtinCommands = append(c.builtinCommands, name)
+}
+
+// isBuiltinCommand returns whether the given command name is a built-in command.
+func (c *DispatcherCommand) isBuiltinCommand(name string) bool {
+	return slices.Contains(c.builtinCommands, name)
+}
+
+// FullName returns the full command name including the parent
+// path, if any (e.g., `example net` for `net` nested under `example`).
+func (c *DispatcherCommand) FullName() string {
+	return strings.Join(c.fullPath(), " ")
+}
+
+// fullPath returns a copy of the Path, falling back to the words of the
+// Name when the Path is empty (e.g., when not using [NewDispatcherCommand]).
+func (c *DispatcherCommand) fullPath() []string {
+	if len(c.Path) <= 0 {
+		return strings.Fields(c.Name)
+	}
+	return slices.Clone(c.Path)
+}
+
+// setPath sets the Path and propagates it to the nested [*DispatcherCommand].
+func (c *DispatcherCommand) setPath(path []string) {
+	c.Path = slices.Clone(path)
+	for name, command := range c.Commands {
+		if child, ok := command.Cmd.(*DispatcherCommand); ok {
+			child.setPath(append(c.fullPath(), name))
+		}
+	}
 }
 
 // MustAddCommandAlias introduces an alias for an existing command.
@@ -152,13 +343,190 @@ func (c *DispatcherCommand) MustAddCommandAlias(curName, newAlias string) {
 	c.CommandNameToAliases[curName] = append(c.CommandNameToAliases[curName], newAlias)
 }
 
+// MustAddCommandShortcut introduces a shortcut for an existing command, that is,
+// an alias expanding into the command followed by the given leading arguments. For
+// example, after `c.MustAddCommandShortcut("ll", "ls", "-l")`, running `ll -a`
+// is equivalent to running `ls -l -a`.
+//
+// Shortcuts take part in completion, suggestions, and prefix matching like command
+// names. The [*DefaultUsagePrinter] lists shortcuts along with their expansion and
+// [Walk] reports them along with the command they expand to. We ignore the shortcuts
+// of hidden commands everywhere except when dispatching.
+//
+// This method panics if target is not an existing command name or alias
+// or if name is already an existing command name or alias.
+func (c *DispatcherCommand) MustAddCommandShortcut(name, target string, args ...string) {
+	_, found := c.findCommand(target)
+	runtimex.Assert(found)
+	_, found = c.findCommand(name)
+	runtimex.Assert(!found)
+	c.CommandShortcuts[name] = append([]string{target}, args...)
+}
+
+// shortcutNames returns the sorted names of the shortcuts of non-hidden commands.
+func (c *DispatcherCommand) shortcutNames() []string {
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(c.CommandShortcuts)) {
+		if !c.shortcutTarget(name).Hidden {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// shortcutTarget returns the command the given shortcut expands to.
+func (c *DispatcherCommand) shortcutTarget(name string) DescribedCommand {
+	return c.Commands[c.resolveAlias(c.CommandShortcuts[name][0])]
+}
+
+// childShortcuts returns the shortcuts expanding to the command with the given
+// name, mapping each shortcut name to the arguments following the command name.
+func (c *DispatcherCommand) childShortcuts(name string) map[string][]string {
+	shortcuts := map[string][]string{}
+	for shortcut, expansion := range c.CommandShortcuts {
+		if c.resolveAlias(expansion[0]) == name {
+			shortcuts[shortcut] = slices.Clone(expansion[1:])
+		}
+	}
+	return shortcuts
+}
+
+// AddCommandGroup adds a command group with the given name.
+//
+// The [*DefaultUsagePrinter] lists the commands belonging to each group under
+// a heading containing the group name, using the order in which groups have
+// been added, followed by the ungrouped and built-in commands.
+func (c *DispatcherCommand) AddCommandGroup(group string) {
+	if !slices.Contains(c.CommandGroups, group) {
+		c.CommandGroups = append(c.CommandGroups, group)
+	}
+}
+
+// MustSetCommandGroup assigns an existing command to an existing group.
+//
+// This method panics if name is not an existing command name or if group
+// has not been added using [*DispatcherCommand.AddCommandGroup].
+func (c *DispatcherCommand) MustSetCommandGroup(name, group string) {
+	command, found := c.Commands[name]
+	runtimex.Assert(found)
+	runtimex.Assert(slices.Contains(c.CommandGroups, group))
+	command.Group = group
+	c.Commands[name] = command
+}
+
+// MustSetCommandWeight sets the weight of an existing command.
+//
+// With [CommandOrderWeight], commands with lower weight come first.
+//
+// This method panics if name is not an existing command name.
+func (c *DispatcherCommand) MustSetCommandWeight(name string, weight int) {
+	command, found := c.Commands[name]
+	runtimex.Assert(found)
+	command.Weight = weight
+	c.Commands[name] = command
+}
+
+// MustHideCommand marks an existing command as hidden.
+//
+// A hidden command is still dispatchable using its name or aliases but does not appear
+// in the help, in the completions, and in the generated documentation. This is useful
+// for debug and maintenance commands that should not clutter the commands list.
+//
+// This method panics if name is not an existing command name.
+func (c *DispatcherCommand) MustHideCommand(name string) {
+	command, found := c.Commands[name]
+	runtimex.Assert(found)
+	command.Hidden = true
+	c.Commands[name] = command
+}
+
+// MustDeprecateCommand marks an existing command as deprecated.
+//
+// The replacement is the name of the command to use instead and the removalVersion
+// is the version that will remove the command. Both are optional.
+//
+// Invoking a deprecated command prints a warning on the Stderr before running it. The
+// [*DefaultUsagePrinter] lists deprecated commands in a separate section, unless they
+// are also hidden using [*DispatcherCommand.MustHideCommand].
+//
+// This method panics if name is not an existing command name.
+func (c *DispatcherCommand) MustDeprecateCommand(name, replacement, removalVersion string) {
+	command, found := c.Commands[name]
+	runtimex.Assert(found)
+	command.Deprecated = &Deprecation{RemovalVersion: removalVersion, Replacement: replacement}
+	c.Commands[name] = command
+}
+
 // findCommand searches for a command taking aliases into account.
 func (c *DispatcherCommand) findCommand(name string) (Command, bool) {
+	cmd, ok := c.Commands[c.resolveAlias(name)]
+	return cmd, ok
+}
+
+// resolveAlias returns the real name of the given alias or the name itself.
+func (c *DispatcherCommand) resolveAlias(name string) string {
 	if realName, ok := c.CommandAliasToName[name]; ok {
-		name = realName
+		return realName
 	}
-	cmd, ok := c.Commands[name]
-	return cmd, ok
+	return name
+}
+
+// lookupCommand is like findCommand but also honors plugins and AllowPrefixMatching
+// and returns the real name of the command along with the command itself.
+//
+// The args MUST contain at least the command name.
+func (c *DispatcherCommand) lookupCommand(args []string) (string, DescribedCommand, error) {
+	runtimex.Assert(len(args) >= 1)
+	name := c.resolveAlias(args[0])
+	if cmd, ok := c.Commands[name]; ok {
+		return name, cmd, nil
+	}
+	if name, plugin, ok := c.findPlugin(args[0]); ok {
+		return name, plugin, nil
+	}
+	if c.AllowPrefixMatching && args[0] != "" {
+		candidates := c.prefixCandidates(args[0])
+		switch {
+		case len(candidates) == 1 && c.CommandShortcuts[candidates[0]] != nil:
+			name := c.resolveAlias(c.CommandShortcuts[candidates[0]][0])
+			return name, c.Commands[name], nil
+		case len(candidates) == 1:
+			return candidates[0], c.Commands[candidates[0]], nil
+		case len(candidates) > 1:
+			return "", DescribedCommand{}, &AmbiguousCommandError{
+				Args:       args[1:],
+				Candidates: candidates,
+				Name:       args[0],
+				Path:       c.FullName(),
+			}
+		}
+	}
+	return "", DescribedCommand{}, c.newCommandNotFoundError(args)
+}
+
+// prefixCandidates returns the sorted names of the non-hidden commands whose
+// name or aliases start with the given prefix and of the shortcuts starting
+// with the given prefix.
+func (c *DispatcherCommand) prefixCandidates(prefix string) []string {
+	var candidates []string
+	for _, name := range slices.Sorted(maps.Keys(c.Commands)) {
+		if c.Commands[name].Hidden {
+			continue
+		}
+		for _, word := range append([]string{name}, c.CommandNameToAliases[name]...) {
+			if strings.HasPrefix(word, prefix) {
+				candidates = append(candidates, name)
+				break
+			}
+		}
+	}
+	for _, name := range c.shortcutNames() {
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name)
+		}
+	}
+	slices.Sort(candidates)
+	return candidates
 }
 
 var _ Command = &DispatcherCommand{}
@@ -166,19 +534,134 @@ var _ Command = &DispatcherCommand{}
 // ErrCommandNotFound indicates that the given command was not found.
 var ErrCommandNotFound = errors.New("command not found")
 
+// CommandNotFoundError is the error returned when a command does not exist.
+//
+// Use [errors.As] to access the fields. This error also matches
+// [ErrCommandNotFound] when using [errors.Is].
+type CommandNotFoundError struct {
+	// Args contains the command line arguments following Name.
+	Args []string
+
+	// Name is the unknown command name.
+	Name string
+
+	// Path is the path of the [*DispatcherCommand] that failed (e.g., `example`).
+	Path string
+
+	// Suggestions contains the command names and aliases similar to Name.
+	Suggestions []string
+}
+
+// newCommandNotFoundError creates a new [*CommandNotFoundError] for the given args.
+func (c *DispatcherCommand) newCommandNotFoundError(args []string) *CommandNotFoundError {
+	runtimex.Assert(len(args) >= 1)
+	return &CommandNotFoundError{
+		Args:        args[1:],
+		Name:        args[0],
+		Path:        c.FullName(),
+		Suggestions: c.suggestCommands(args[0]),
+	}
+}
+
+// Error implements error.
+func (err *CommandNotFoundError) Error() string {
+	msg := fmt.Sprintf("%s: %s%s", ErrCommandNotFound.Error(), err.Name, formatSuggestions(err.Suggestions))
+	if err.Path == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", err.Path, msg)
+}
+
+// Unwrap returns [ErrCommandNotFound].
+func (err *CommandNotFoundError) Unwrap() error {
+	return ErrCommandNotFound
+}
+
+// ErrAmbiguousCommand indicates that a command prefix matches several commands.
+var ErrAmbiguousCommand = errors.New("ambiguous command")
+
+// AmbiguousCommandError is the error returned when [*DispatcherCommand] uses
+// AllowPrefixMatching and the given prefix matches several commands.
+//
+// Use [errors.As] to access the fields. This error also matches
+// [ErrAmbiguousCommand] when using [errors.Is].
+type AmbiguousCommandError struct {
+	// Args contains the command line arguments following Name.
+	Args []string
+
+	// Candidates contains the names of the commands matching Name.
+	Candidates []string
+
+	// Name is the ambiguous command name prefix.
+	Name string
+
+	// Path is the path of the [*DispatcherCommand] that failed (e.g., `example`).
+	Path string
+}
+
+// Error implements error.
+func (err *AmbiguousCommandError) Error() string {
+	msg := fmt.Sprintf("%s: %s (could be %s)", ErrAmbiguousCommand.Error(), err.Name, quoteWords(err.Candidates))
+	if err.Path == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", err.Path, msg)
+}
+
+// Unwrap returns [ErrAmbiguousCommand].
+func (err *AmbiguousCommandError) Unwrap() error {
+	return ErrAmbiguousCommand
+}
+
 // Main implements [Command].
 func (c *DispatcherCommand) Main(ctx context.Context, args []string) error {
 	return c.maybeHandleError(c.main(ctx, args))
 }
 
 func (c *DispatcherCommand) main(ctx context.Context, args []string) error {
+	ctx = c.withEnvironment(ctx, c.fullPath())
+	if len(args) > 0 && args[0] == completeSubcommandName {
+		return c.completeMain(ctx, args[1:])
+	}
+	ctx, args, err := c.parseGlobalFlags(ctx, args)
+	if err != nil {
+		return err
+	}
+	ctx, err = c.maybeLoadConfig(ctx)
+	if err == nil {
+		err = c.loadUserAliases(ConfigSectionFromContext(ctx))
+	}
+	if err != nil && (len(args) <= 0 || c.resolveAlias(args[0]) == "help") {
+		// do not prevent users from getting help because of a broken config
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
 	if len(args) <= 0 {
 		return c.helpMain(ctx, args)
 	}
-	if child, ok := c.findCommand(args[0]); ok {
-		return child.Main(ctx, args[1:])
+	if args, err = c.expandAliases(args); err != nil {
+		return err
+	}
+	name, child, err := c.lookupCommand(args)
+	if err != nil {
+		return c.maybeRecoverErrCommandNotFound(args, err)
+	}
+	if child.Deprecated != nil {
+		must.Fprintf(c.Stderr, "%s: warning: `%s' is deprecated%s\n", c.FullName(), name, child.Deprecated.details())
+	}
+	if c.isBuiltinCommand(name) {
+		// the built-in commands act on this dispatcher, so they use its config section
+		return child.Cmd.Main(withoutMiddlewares(ctx), args[1:])
 	}
-	return c.maybeRecoverErrCommandNotFound(args)
+	ctx, chain := c.withMiddlewares(ctx)
+	cmd := child.Cmd
+	if _, ok := cmd.(*DispatcherCommand); !ok {
+		ctx = c.withEnvironment(ctx, append(c.fullPath(), name))
+		cmd = wrapCommand(cmd, chain)
+	}
+	return cmd.Main(withConfigSubsection(ctx, name), args[1:])
 }
 
 func (c *DispatcherCommand) maybeHandleError(err error) error {
@@ -191,13 +674,21 @@ func (c *DispatcherCommand) maybeHandleError(err error) error {
 		return err
 
 	case c.ErrorHandling == vflag.ExitOnError:
-		must.Fprintf(c.Stderr, "%s\n", err.Error())
+		code, printable := exitStatus(err)
+		if printable {
+			must.Fprintf(c.Stderr, "%s\n", err.Error())
+		}
+		var coder ExitCoder
 		switch {
-		case errors.Is(err, ErrCommandNotFound):
-			must.Fprintf(c.Stderr, "%s: use `%s --help' to see the available commands\n", c.Name, c.Name)
+		case errors.As(err, &coder):
+			// an explicit exit code wins over the lookup errors it may wrap
+			c.Exit(code)
+		case errors.Is(err, ErrCommandNotFound), errors.Is(err, ErrAmbiguousCommand):
+			path := c.lookupErrorPath(err)
+			must.Fprintf(c.Stderr, "%s: use `%s --help' to see the available commands\n", path, path)
 			c.Exit(2)
 		default:
-			c.Exit(1)
+			c.Exit(code)
 		}
 	}
 
@@ -206,15 +697,31 @@ func (c *DispatcherCommand) maybeHandleError(err error) error {
 	panic(err)
 }
 
-// maybeRecoverErrCommandNotFound recovers from an [ErrCommandNotFound] error condition
-// when the `--help` or the `-h` flag appears at the end of the command line.
+// lookupErrorPath returns the path of the [*DispatcherCommand] that failed to look up a
+// command, which differs from our own path when the failure occurred in a nested
+// [*DispatcherCommand] whose ErrorHandling is [vflag.ContinueOnError].
+func (c *DispatcherCommand) lookupErrorPath(err error) string {
+	var notFound *CommandNotFoundError
+	if errors.As(err, &notFound) && notFound.Path != "" {
+		return notFound.Path
+	}
+	var ambiguous *AmbiguousCommandError
+	if errors.As(err, &ambiguous) && ambiguous.Path != "" {
+		return ambiguous.Path
+	}
+	return c.FullName()
+}
+
+// maybeRecoverErrCommandNotFound recovers from an [ErrCommandNotFound] or
+// [ErrAmbiguousCommand] error condition when the `--help` or the `-h` flag appears
+// at the end of the command line. Otherwise, it returns the original error.
 //
 // This specifically enables the UX pattern where the user appends `-h` or `--help` to
 // the command line, however wrong, and always gets the usage.
-func (c *DispatcherCommand) maybeRecoverErrCommandNotFound(args []string) error {
+func (c *DispatcherCommand) maybeRecoverErrCommandNotFound(args []string, err error) error {
 	total := len(args)
 	if total < 1 || (args[total-1] != "--help" && args[total-1] != "-h") {
-		return fmt.Errorf("%s: %w: %s", c.Name, ErrCommandNotFound, args[0])
+		return err
 	}
 	return c.printHelp()
 }
@@ -160,3 +160,534 @@ func TestDispatcherCommandVersionNotRegisteredByDefault(t *testing.T) {
 	err := disp.Main(context.Background(), []string{"version"})
 	require.ErrorIs(t, err, ErrCommandNotFound)
 }
+
+func TestDispatcherCommandMainCommandNotFoundError(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("curl", &testCommand{})
+
+	err := disp.Main(context.Background(), []string{"crul", "-fsSL", "https://example.com/"})
+	require.ErrorIs(t, err, ErrCommandNotFound)
+
+	var notFound *CommandNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "example", notFound.Path)
+	assert.Equal(t, "crul", notFound.Name)
+	assert.Equal(t, []string{"-fsSL", "https://example.com/"}, notFound.Args)
+	assert.Equal(t, []string{"curl"}, notFound.Suggestions)
+}
+
+func TestDispatcherCommandHelpMainCommandNotFoundError(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	var stderr bytes.Buffer
+	disp.Stderr = &stderr
+
+	err := disp.Main(context.Background(), []string{"help", "nope"})
+
+	var notFound *CommandNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "example", notFound.Path)
+	assert.Equal(t, "nope", notFound.Name)
+	assert.Empty(t, notFound.Args)
+	assert.Contains(t, stderr.String(), "example help: command not found: nope\n")
+}
+
+func TestCommandNotFoundErrorWithoutPath(t *testing.T) {
+	err := &CommandNotFoundError{Name: "nope"}
+	assert.Equal(t, "command not found: nope", err.Error())
+}
+
+func TestDispatcherCommandMainPrefixMatching(t *testing.T) {
+	var invoked []string
+	newCommand := func(name string) Command {
+		return CommandFunc(func(ctx context.Context, args []string) error {
+			invoked = append(invoked, name)
+			return nil
+		})
+	}
+	var stdout, stderr bytes.Buffer
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AllowPrefixMatching = true
+	disp.Stderr = &stderr
+	disp.Stdout = &stdout
+	disp.AddCommand("curl", newCommand("curl"))
+	disp.AddCommand("cat", newCommand("cat"))
+	disp.AddCommand("dig", newCommand("dig"))
+	disp.MustAddCommandAlias("dig", "dog")
+
+	t.Run("unambiguous prefixes", func(t *testing.T) {
+		invoked = nil
+		require.NoError(t, disp.Main(context.Background(), []string{"cu"}))
+		require.NoError(t, disp.Main(context.Background(), []string{"d"}))
+		require.NoError(t, disp.Main(context.Background(), []string{"do"}))
+		require.NoError(t, disp.Main(context.Background(), []string{"cat"}))
+		assert.Equal(t, []string{"curl", "dig", "dig", "cat"}, invoked)
+	})
+
+	t.Run("ambiguous prefix", func(t *testing.T) {
+		invoked = nil
+		err := disp.Main(context.Background(), []string{"c", "-v"})
+		require.ErrorIs(t, err, ErrAmbiguousCommand)
+		assert.Equal(t, "example: ambiguous command: c (could be `cat' or `curl')", err.Error())
+
+		var ambiguous *AmbiguousCommandError
+		require.ErrorAs(t, err, &ambiguous)
+		assert.Equal(t, []string{"cat", "curl"}, ambiguous.Candidates)
+		assert.Equal(t, []string{"-v"}, ambiguous.Args)
+		assert.Empty(t, invoked)
+	})
+
+	t.Run("ambiguous prefix with the help flag", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{"c", "--help"})
+		require.NoError(t, err)
+		assert.Contains(t, stdout.String(), "Usage")
+	})
+
+	t.Run("ambiguous prefix with the help subcommand", func(t *testing.T) {
+		stderr.Reset()
+		err := disp.Main(context.Background(), []string{"help", "c"})
+		require.ErrorIs(t, err, ErrAmbiguousCommand)
+		assert.Contains(t, stderr.String(), "example help: ambiguous command: c (could be `cat' or `curl')\n")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		invoked = nil
+		disp.AllowPrefixMatching = false
+		err := disp.Main(context.Background(), []string{"cu"})
+		require.ErrorIs(t, err, ErrCommandNotFound)
+		assert.Empty(t, invoked)
+	})
+}
+
+func TestDispatcherCommandMainExitOnErrorAmbiguousCommand(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ExitOnError)
+	disp.AllowPrefixMatching = true
+	disp.AddCommand("curl", &testCommand{})
+	disp.AddCommand("cat", &testCommand{})
+
+	status, _, stderr := runMainExpectExit(t, disp, []string{"c"})
+
+	assert.Equal(t, 2, status)
+	assert.Contains(t, stderr, "example: ambiguous command: c")
+	assert.Contains(t, stderr, "example: use `example --help'")
+}
+
+func TestDispatcherCommandHiddenCommand(t *testing.T) {
+	newDispatcher := func() (*DispatcherCommand, *bool) {
+		invoked := false
+		disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+		disp.AllowPrefixMatching = true
+		disp.AddCommand("curl", &testCommand{}, "Utility to transfer URLs.")
+		disp.AddCommand("debug", CommandFunc(func(ctx context.Context, args []string) error {
+			invoked = true
+			return nil
+		}), "Internal debugging command.")
+		disp.MustAddCommandAlias("debug", "dbg")
+		disp.MustHideCommand("debug")
+		return disp, &invoked
+	}
+
+	t.Run("it is dispatchable using its name", func(t *testing.T) {
+		disp, invoked := newDispatcher()
+		require.NoError(t, disp.Main(context.Background(), []string{"debug"}))
+		assert.True(t, *invoked)
+	})
+
+	t.Run("it is dispatchable using its aliases", func(t *testing.T) {
+		disp, invoked := newDispatcher()
+		require.NoError(t, disp.Main(context.Background(), []string{"dbg"}))
+		assert.True(t, *invoked)
+	})
+
+	t.Run("it is not selected by prefix", func(t *testing.T) {
+		disp, invoked := newDispatcher()
+		require.ErrorIs(t, disp.Main(context.Background(), []string{"deb"}), ErrCommandNotFound)
+		assert.False(t, *invoked)
+	})
+
+	t.Run("it is not suggested", func(t *testing.T) {
+		disp, _ := newDispatcher()
+		assert.Empty(t, disp.suggestCommands("debgu"))
+	})
+
+	t.Run("it is not in the help", func(t *testing.T) {
+		disp, _ := newDispatcher()
+		var stdout bytes.Buffer
+		disp.Stdout = &stdout
+		require.NoError(t, disp.Main(context.Background(), []string{"--help"}))
+		assert.Contains(t, stdout.String(), "curl")
+		assert.NotContains(t, stdout.String(), "debug")
+		assert.NotContains(t, stdout.String(), "dbg")
+	})
+
+	t.Run("it is not completed", func(t *testing.T) {
+		disp, _ := newDispatcher()
+		completions, err := disp.Complete(context.Background(), []string{"d"})
+		require.NoError(t, err)
+		assert.Empty(t, completions)
+	})
+}
+
+func TestDispatcherCommandMustHideCommandPanicsForUnknownCommand(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	assert.Panics(t, func() {
+		disp.MustHideCommand("nope")
+	})
+}
+
+func TestDispatcherCommandDeprecatedCommand(t *testing.T) {
+	testCases := []struct {
+		name           string
+		replacement    string
+		removalVersion string
+		expect         string
+	}{{
+		name:   "without details",
+		expect: "example: warning: `fetch' is deprecated\n",
+	}, {
+		name:        "with replacement",
+		replacement: "curl",
+		expect:      "example: warning: `fetch' is deprecated; use `curl' instead\n",
+	}, {
+		name:           "with removal version",
+		removalVersion: "v2.0.0",
+		expect:         "example: warning: `fetch' is deprecated and will be removed in v2.0.0\n",
+	}, {
+		name:           "with replacement and removal version",
+		replacement:    "curl",
+		removalVersion: "v2.0.0",
+		expect:         "example: warning: `fetch' is deprecated and will be removed in v2.0.0; use `curl' instead\n",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotArgs []string
+			disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+			disp.AddCommand("fetch", CommandFunc(func(ctx context.Context, args []string) error {
+				gotArgs = args
+				return nil
+			}))
+			disp.MustAddCommandAlias("fetch", "f")
+			disp.MustDeprecateCommand("fetch", tc.replacement, tc.removalVersion)
+			var stderr bytes.Buffer
+			disp.Stderr = &stderr
+
+			require.NoError(t, disp.Main(context.Background(), []string{"f", "https://example.com/"}))
+			assert.Equal(t, tc.expect, stderr.String())
+			assert.Equal(t, []string{"https://example.com/"}, gotArgs)
+		})
+	}
+}
+
+func TestDispatcherCommandDeprecatedAndHiddenCommand(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("fetch", &testCommand{}, "Utility to transfer URLs.")
+	disp.MustDeprecateCommand("fetch", "", "")
+	disp.MustHideCommand("fetch")
+	var stdout bytes.Buffer
+	disp.Stdout = &stdout
+
+	require.NoError(t, disp.Main(context.Background(), []string{"--help"}))
+	assert.NotContains(t, stdout.String(), "Deprecated")
+	assert.NotContains(t, stdout.String(), "fetch")
+}
+
+func TestDispatcherCommandDeprecatedCommandNotCompleted(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("fetch", &testCommand{}, "Utility to transfer URLs.")
+	disp.MustDeprecateCommand("fetch", "", "")
+
+	completions, err := disp.Complete(context.Background(), []string{"f"})
+	require.NoError(t, err)
+	assert.Empty(t, completions)
+}
+
+func TestDispatcherCommandPathOnAddCommand(t *testing.T) {
+	curl := NewDispatcherCommand("curl", vflag.ContinueOnError)
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddCommand("curl", curl)
+	assert.Equal(t, []string{"net", "curl"}, curl.Path)
+	assert.Equal(t, "net curl", curl.FullName())
+
+	// adding the parent later propagates the path to the children
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	assert.Equal(t, []string{"example"}, disp.Path)
+	assert.Equal(t, "example", disp.FullName())
+	disp.AddCommand("net", net)
+	assert.Equal(t, []string{"example", "net"}, net.Path)
+	assert.Equal(t, "example net", net.FullName())
+	assert.Equal(t, []string{"example", "net", "curl"}, curl.Path)
+	assert.Equal(t, "example net curl", curl.FullName())
+}
+
+func TestDispatcherCommandPathUsesRegisteredName(t *testing.T) {
+	t.Run("name containing the parent path", func(t *testing.T) {
+		net := NewDispatcherCommand("example net", vflag.ContinueOnError)
+		assert.Equal(t, "example net", net.FullName())
+		disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+		disp.AddCommand("net", net)
+		assert.Equal(t, "example net", net.FullName())
+
+		err := disp.Main(context.Background(), []string{"net", "nope"})
+		assert.Equal(t, "example net: command not found: nope", err.Error())
+	})
+
+	t.Run("name differing from the registered name", func(t *testing.T) {
+		net := NewDispatcherCommand("network", vflag.ContinueOnError)
+		disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+		disp.AddCommand("net", net)
+		assert.Equal(t, "example net", net.FullName())
+
+		err := disp.Main(context.Background(), []string{"net", "nope"})
+		assert.Equal(t, "example net: command not found: nope", err.Error())
+	})
+
+	t.Run("without NewDispatcherCommand", func(t *testing.T) {
+		disp := &DispatcherCommand{Name: "example net"}
+		assert.Equal(t, "example net", disp.FullName())
+	})
+}
+
+func TestDispatcherCommandPathNotChangedByMain(t *testing.T) {
+	// a dispatcher added directly to the Commands map keeps its own path
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.Commands["net"] = NewDescribedCommand(net)
+
+	err := disp.Main(context.Background(), []string{"net", "nope"})
+	var notFound *CommandNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "net", notFound.Path)
+	assert.Equal(t, []string{"net"}, net.Path)
+}
+
+func TestDispatcherCommandNestedUsageAndErrors(t *testing.T) {
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddCommand("curl", &testCommand{}, "Utility to transfer URLs.")
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("net", net, "Network commands.")
+
+	t.Run("usage", func(t *testing.T) {
+		var stdout bytes.Buffer
+		disp.Stdout, net.Stdout = &stdout, &stdout
+		require.NoError(t, disp.Main(context.Background(), []string{"net", "--help"}))
+		assert.Contains(t, stdout.String(), "\n    example net <command> [args...]\n")
+		assert.Contains(t, stdout.String(), "Use `example net <command> --help' to get command-specific help.")
+	})
+
+	t.Run("help subcommand error", func(t *testing.T) {
+		var stderr bytes.Buffer
+		net.Stderr = &stderr
+		err := disp.Main(context.Background(), []string{"net", "help", "nope"})
+		require.ErrorIs(t, err, ErrCommandNotFound)
+		assert.Contains(t, stderr.String(), "example net help: command not found: nope\n")
+	})
+
+	t.Run("exit on error hint", func(t *testing.T) {
+		var stderr bytes.Buffer
+		var status int
+		net.ErrorHandling = vflag.ExitOnError
+		net.Stderr = &stderr
+		net.Exit = func(code int) { status = code; panic("exit") }
+		assert.PanicsWithValue(t, "exit", func() {
+			disp.Main(context.Background(), []string{"net", "crul"})
+		})
+		assert.Equal(t, 2, status)
+		assert.Equal(t, "example net: command not found: crul (did you mean `curl'?)\n"+
+			"example net: use `example net --help' to see the available commands\n", stderr.String())
+	})
+
+	t.Run("exit on error hint from the outer dispatcher", func(t *testing.T) {
+		net.ErrorHandling = vflag.ContinueOnError
+		disp.ErrorHandling = vflag.ExitOnError
+		status, _, stderr := runMainExpectExit(t, disp, []string{"net", "crul"})
+		assert.Equal(t, 2, status)
+		assert.Equal(t, "example net: command not found: crul (did you mean `curl'?)\n"+
+			"example net: use `example net --help' to see the available commands\n", stderr)
+	})
+}
+
+func TestDispatcherCommandHelpMainCommandPath(t *testing.T) {
+	var curlArgs []string
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddCommand("curl", CommandFunc(func(ctx context.Context, args []string) error {
+		curlArgs = args
+		return nil
+	}), "Utility to transfer URLs.")
+	net.MustAddCommandAlias("curl", "c")
+
+	var stdout, stderr bytes.Buffer
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.Stderr, net.Stderr = &stderr, &stderr
+	disp.Stdout, net.Stdout = &stdout, &stdout
+	disp.AddCommand("net", net, "Network commands.")
+	disp.MustAddCommandAlias("net", "n")
+
+	t.Run("names and aliases", func(t *testing.T) {
+		for _, args := range [][]string{{"help", "net", "curl"}, {"help", "n", "c"}} {
+			curlArgs = nil
+			err := disp.Main(context.Background(), args)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"--help"}, curlArgs)
+		}
+	})
+
+	t.Run("nested dispatcher", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{"help", "n"})
+		require.NoError(t, err)
+		assert.Contains(t, stdout.String(), "\n    example net <command> [args...]\n")
+	})
+
+	errorCases := []struct {
+		args   []string
+		path   string
+		name   string
+		stderr string
+	}{{
+		args:   []string{"help", "nope", "curl"},
+		path:   "example",
+		name:   "nope",
+		stderr: "example help: command not found: nope\n",
+	}, {
+		args:   []string{"help", "net", "crul"},
+		path:   "example net",
+		name:   "crul",
+		stderr: "example help: example net: command not found: crul (did you mean `curl'?)\n",
+	}, {
+		args:   []string{"help", "net", "curl", "extra"},
+		path:   "example net curl",
+		name:   "extra",
+		stderr: "example help: example net curl: command not found: extra\n",
+	}}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			curlArgs = nil
+			stderr.Reset()
+			err := disp.Main(context.Background(), tc.args)
+			var notFound *CommandNotFoundError
+			require.ErrorAs(t, err, &notFound)
+			assert.Equal(t, tc.path, notFound.Path)
+			assert.Equal(t, tc.name, notFound.Name)
+			assert.Contains(t, stderr.String(), tc.stderr)
+			assert.Nil(t, curlArgs)
+		})
+	}
+}
+
+func TestDispatcherCommandMustAddCommandShortcut(t *testing.T) {
+	newTestDispatcher := func(ls Command) *DispatcherCommand {
+		disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+		disp.AddCommand("ls", ls, "List directory contents.")
+		disp.MustAddCommandAlias("ls", "dir")
+		disp.MustAddCommandShortcut("ll", "dir", "-l")
+		disp.MustAddCommandShortcut("la", "ls", "-a", "--sort=name time")
+		return disp
+	}
+
+	t.Run("we prepend the expansion to the remaining args", func(t *testing.T) {
+		var lsArgs []string
+		disp := newTestDispatcher(saveArgs(&lsArgs))
+		require.NoError(t, disp.Main(context.Background(), []string{"ll", "-h", "/tmp"}))
+		assert.Equal(t, []string{"-l", "-h", "/tmp"}, lsArgs)
+	})
+
+	t.Run("shortcuts win over user aliases", func(t *testing.T) {
+		var lsArgs []string
+		disp := newTestDispatcher(saveArgs(&lsArgs))
+		disp.UserAliases["ll"] = []string{"ls", "-1"}
+		disp.UserAliases["l"] = []string{"ll", "-t"}
+		require.NoError(t, disp.Main(context.Background(), []string{"l"}))
+		assert.Equal(t, []string{"-l", "-t"}, lsArgs)
+		assert.Equal(t, []string{"l"}, disp.userAliasNames())
+	})
+
+	t.Run("the help lists shortcuts with their expansion", func(t *testing.T) {
+		disp := newTestDispatcher(&testCommand{})
+		var stdout bytes.Buffer
+		disp.Stdout = &stdout
+		require.NoError(t, disp.Main(context.Background(), []string{"--help"}))
+		assert.Contains(t, stdout.String(), "\nShortcuts\n\n    la\n\n"+
+			"        Shortcut for `ls -a '--sort=name time''.\n\n    ll\n\n        Shortcut for `dir -l'.\n")
+	})
+
+	t.Run("we complete shortcuts and route their completion", func(t *testing.T) {
+		disp := newTestDispatcher(&testCommand{})
+		completions, err := disp.Complete(context.Background(), []string{"l"})
+		require.NoError(t, err)
+		assert.Equal(t, []Completion{
+			{Value: "ls", Descr: "List directory contents."},
+			{Value: "la", Descr: "Shortcut for `ls -a '--sort=name time''."},
+			{Value: "ll", Descr: "Shortcut for `dir -l'."},
+		}, completions)
+
+		var treeArgs []string
+		disp.AddCommand("tree", &testCompleterCommand{CompleterFunc: func(ctx context.Context, args []string) ([]Completion, error) {
+			treeArgs = args
+			return nil, nil
+		}}, "List directory trees.")
+		disp.MustAddCommandShortcut("t1", "tree", "-L", "1")
+		_, err = disp.Complete(context.Background(), []string{"t1", "/"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"-L", "1", "/"}, treeArgs)
+	})
+
+	t.Run("prefix matching considers shortcuts", func(t *testing.T) {
+		var lsArgs []string
+		disp := newTestDispatcher(saveArgs(&lsArgs))
+		disp.AllowPrefixMatching = true
+		err := disp.Main(context.Background(), []string{"l"})
+		var ambiguous *AmbiguousCommandError
+		require.ErrorAs(t, err, &ambiguous)
+		assert.Equal(t, []string{"la", "ll", "ls"}, ambiguous.Candidates)
+
+		require.NoError(t, disp.Main(context.Background(), []string{"la", "/tmp"}))
+		disp.MustAddCommandShortcut("xl", "ls", "-x")
+		require.NoError(t, disp.Main(context.Background(), []string{"x", "/tmp"}))
+		assert.Equal(t, []string{"-x", "/tmp"}, lsArgs)
+	})
+
+	t.Run("the help subcommand resolves shortcuts", func(t *testing.T) {
+		disp := newTestDispatcher(&testCommand{})
+		var stderr bytes.Buffer
+		disp.Stderr = &stderr
+		net := NewDispatcherCommand("net", vflag.ContinueOnError)
+		net.AddCommand("curl", &testCommand{}, "Utility to transfer URLs.")
+		disp.AddCommand("net", net, "Network commands.")
+		disp.MustAddCommandShortcut("get", "net", "curl", "-fsSL")
+
+		names, cmd, err := disp.lookupCommandPath(context.Background(), []string{"ll"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ls"}, names)
+		assert.Equal(t, []string{"List directory contents."}, cmd.Descr)
+
+		names, _, err = disp.lookupCommandPath(context.Background(), []string{"get"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"net", "curl"}, names)
+
+		require.NoError(t, disp.Main(context.Background(), []string{"help", "ll"}))
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("we suggest shortcuts", func(t *testing.T) {
+		disp := newTestDispatcher(&testCommand{})
+		err := disp.Main(context.Background(), []string{"lk"})
+		require.ErrorIs(t, err, ErrCommandNotFound)
+		assert.Equal(t, []string{"la", "ll", "ls"}, disp.suggestCommands("lk"))
+	})
+
+	t.Run("we ignore the shortcuts of hidden commands", func(t *testing.T) {
+		disp := newTestDispatcher(&testCommand{})
+		disp.MustHideCommand("ls")
+		assert.Empty(t, disp.shortcutNames())
+	})
+
+	t.Run("we panic for an unknown target", func(t *testing.T) {
+		disp := newTestDispatcher(&testCommand{})
+		assert.Panics(t, func() { disp.MustAddCommandShortcut("lt", "tree") })
+	})
+
+	t.Run("we panic when the name is a command", func(t *testing.T) {
+		disp := newTestDispatcher(&testCommand{})
+		assert.Panics(t, func() { disp.MustAddCommandShortcut("dir", "ls", "-l") })
+	})
+}
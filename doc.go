@@ -9,9 +9,11 @@
 //
 // Design tradeoffs and behavior:
 //
-//   - No global flag parsing: arguments before the subcommand are not reshuffled
-//     or guessed, because ownership is ambiguous when subcommands have different
-//     conventions.
+//   - No implicit global flag parsing: arguments before the subcommand are not
+//     reshuffled or guessed, because ownership is ambiguous when subcommands have
+//     different conventions. A dispatcher may opt in to declaring a small set of
+//     global flags, which must appear before the subcommand name and whose values
+//     reach the subcommands through the context.
 //
 //   - Help is universal: the dispatcher treats "-h" and "--help" as aliases for
 //     the built-in help command, and appending "-h/--help" to a failing top-level
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import "github.com/bassosimone/vflag"
+
+// newDocsTestDispatcher returns the command tree shared by the tests
+// of the documentation generators and of the APIs they use.
+func newDocsTestDispatcher() *DispatcherCommand {
+	net := NewDispatcherCommand("example net", vflag.ContinueOnError)
+	net.AddDescription("Network commands.", "    example net curl https://example.com/")
+	net.AddCommand(
+		"curl",
+		&testCommand{},
+		"Utility to transfer URLs.",
+		"    curl -fsSL https://example.com/",
+		"Follows redirects.",
+	)
+	net.MustAddCommandAlias("curl", "c")
+	net.MustAddCommandShortcut("get", "curl", "-fsSL")
+
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddDescription("Dispatcher for network commands.")
+	disp.AddCommand("net", net, "Network-related commands.")
+	disp.AddCommand("debug", &testCommand{}, "Internal debugging command.")
+	disp.MustHideCommand("debug")
+	disp.AddCommand("fetch", &testCommand{}, "Old name of curl.")
+	disp.MustDeprecateCommand("fetch", "net curl", "v2.0.0")
+	return disp
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bassosimone/runtimex"
+)
+
+// EnvVar is an environment variable declared using [*DispatcherCommand.AddEnvVar]
+// or [*DispatcherCommand.MustAddCommandEnvVar].
+type EnvVar struct {
+	// Descr contains the environment variable description.
+	Descr []string
+
+	// Name is the environment variable name without the prefix (e.g., `TIMEOUT`).
+	Name string
+}
+
+// AddEnvVar declares an environment variable applying to the [*DispatcherCommand]
+// and to all the commands reachable from it.
+//
+// The full variable name consists of the prefix derived from the command path
+// followed by the given name (e.g., `EXAMPLE_NET_TIMEOUT` for `TIMEOUT` declared
+// by `example net`). Commands read the values using [EnvironmentFromContext].
+func (c *DispatcherCommand) AddEnvVar(name string, descr ...string) {
+	c.EnvVars = append(c.EnvVars, EnvVar{Descr: descr, Name: name})
+}
+
+// MustAddCommandEnvVar is like [*DispatcherCommand.AddEnvVar] but declares an
+// environment variable applying to the given command only (e.g., `EXAMPLE_NET_CURL_TIMEOUT`
+// for `TIMEOUT` declared for the `curl` command of `example net`).
+//
+// This method panics if the command does not exist.
+func (c *DispatcherCommand) MustAddCommandEnvVar(command, name string, descr ...string) {
+	cmd, found := c.Commands[command]
+	runtimex.Assert(found)
+	cmd.EnvVars = append(cmd.EnvVars, EnvVar{Descr: descr, Name: name})
+	c.Commands[command] = cmd
+}
+
+// envVarPrefix returns the environment variable prefix for the given command path
+// (e.g., `EXAMPLE_NET_CURL` for `example net curl`). We map each character that is
+// not an ASCII letter or digit to an underscore.
+func envVarPrefix(path []string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, strings.Join(path, "_"))
+}
+
+// declaredEnvVars returns the full names and the environment variables declared
+// by the [*DispatcherCommand] and by its non-hidden commands.
+func (c *DispatcherCommand) declaredEnvVars() ([]string, []EnvVar) {
+	var (
+		names []string
+		vars  []EnvVar
+	)
+	for _, envVar := range c.EnvVars {
+		names = append(names, envVarPrefix(c.fullPath())+"_"+envVar.Name)
+		vars = append(vars, envVar)
+	}
+	for _, name := range c.SortedCommandNames() {
+		if c.Commands[name].Hidden {
+			continue
+		}
+		for _, envVar := range c.Commands[name].EnvVars {
+			names = append(names, envVarPrefix(append(c.fullPath(), name))+"_"+envVar.Name)
+			vars = append(vars, envVar)
+		}
+	}
+	return names, vars
+}
+
+// environmentKey is the context key for [*Environment].
+type environmentKey struct{}
+
+// Environment provides typed access to the environment variables
+// of the [*DispatcherCommand] instances along the command path.
+//
+// Obtain using [EnvironmentFromContext].
+type Environment struct {
+	// lookupEnv is the function to lookup environment variables.
+	lookupEnv func(key string) (string, bool)
+
+	// prefixes contains the prefixes from the most to the least specific.
+	prefixes []string
+}
+
+// EnvironmentFromContext returns the [*Environment] stored in the context.
+//
+// The return value is never nil, yet it does not find any
+// variable outside of a [*DispatcherCommand] invocation.
+func EnvironmentFromContext(ctx context.Context) *Environment {
+	if env, ok := ctx.Value(environmentKey{}).(*Environment); ok {
+		return env
+	}
+	return &Environment{lookupEnv: nil, prefixes: []string{}}
+}
+
+// withEnvironment returns a context whose [*Environment] includes the prefix derived
+// from the given command path. The outermost [*DispatcherCommand] provides the
+// function used to lookup environment variables, that is, its LookupEnv.
+func (c *DispatcherCommand) withEnvironment(ctx context.Context, path []string) context.Context {
+	env := EnvironmentFromContext(ctx)
+	prefix := envVarPrefix(path)
+	if len(env.prefixes) > 0 && env.prefixes[0] == prefix {
+		return ctx
+	}
+	lookupEnv := env.lookupEnv
+	if lookupEnv == nil {
+		lookupEnv = c.LookupEnv
+	}
+	return context.WithValue(ctx, environmentKey{}, &Environment{
+		lookupEnv: lookupEnv,
+		prefixes:  append([]string{prefix}, env.prefixes...),
+	})
+}
+
+// lookup returns the full name and the value of the given variable using the
+// most specific prefix for which the variable exists.
+func (env *Environment) lookup(name string) (string, string, bool) {
+	for _, prefix := range env.prefixes {
+		key := prefix + "_" + name
+		if value, found := env.lookupEnv(key); found {
+			return key, value, true
+		}
+	}
+	return "", "", false
+}
+
+// Lookup returns the value of the given environment variable, where the name does
+// not include the prefix (e.g., `TIMEOUT`), and whether the variable exists.
+//
+// We try the prefixes of the commands along the command path, from the most to
+// the least specific. For example, when running `example net curl`, we look up
+// `EXAMPLE_NET_CURL_TIMEOUT`, then `EXAMPLE_NET_TIMEOUT`, then `EXAMPLE_TIMEOUT`.
+func (env *Environment) Lookup(name string) (string, bool) {
+	_, value, found := env.lookup(name)
+	return value, found
+}
+
+// String is like Lookup but returns the fallback value when the variable does not exist.
+func (env *Environment) String(name, fallback string) string {
+	if value, found := env.Lookup(name); found {
+		return value
+	}
+	return fallback
+}
+
+// Bool is like String but parses the value using [strconv.ParseBool].
+func (env *Environment) Bool(name string, fallback bool) (bool, error) {
+	return lookupAndParse(env, name, fallback, strconv.ParseBool)
+}
+
+// Int is like String but parses the value using [strconv.Atoi].
+func (env *Environment) Int(name string, fallback int) (int, error) {
+	return lookupAndParse(env, name, fallback, strconv.Atoi)
+}
+
+// Duration is like String but parses the value using [time.ParseDuration].
+func (env *Environment) Duration(name string, fallback time.Duration) (time.Duration, error) {
+	return lookupAndParse(env, name, fallback, time.ParseDuration)
+}
+
+// lookupAndParse implements the typed [*Environment] methods.
+func lookupAndParse[T any](env *Environment, name string, fallback T, parse func(string) (T, error)) (T, error) {
+	key, value, found := env.lookup(name)
+	if !found {
+		return fallback, nil
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironment(t *testing.T) {
+	var (
+		env  *Environment
+		vars map[string]string
+	)
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddEnvVar("PROXY", "Proxy URL to use.")
+	net.AddCommand("curl", CommandFunc(func(ctx context.Context, args []string) error {
+		env = EnvironmentFromContext(ctx)
+		return nil
+	}), "Utility to transfer URLs.")
+	net.MustAddCommandEnvVar("curl", "TIMEOUT", "Timeout for transferring URLs.")
+
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.LookupEnv = func(key string) (string, bool) {
+		value, found := vars[key]
+		return value, found
+	}
+	disp.AddEnvVar("TIMEOUT", "Default timeout for all commands.")
+	disp.AddCommand("net", net, "Network commands.")
+
+	lookupCases := []struct {
+		name   string
+		vars   map[string]string
+		expect string
+		found  bool
+	}{{
+		name: "not found",
+		vars: map[string]string{"TIMEOUT": "1s", "EXAMPLE_NET_CURL": "1s"},
+	}, {
+		name:   "least specific",
+		vars:   map[string]string{"EXAMPLE_TIMEOUT": "1s"},
+		expect: "1s",
+		found:  true,
+	}, {
+		name:   "intermediate",
+		vars:   map[string]string{"EXAMPLE_TIMEOUT": "1s", "EXAMPLE_NET_TIMEOUT": "2s"},
+		expect: "2s",
+		found:  true,
+	}, {
+		name:   "most specific",
+		vars:   map[string]string{"EXAMPLE_TIMEOUT": "1s", "EXAMPLE_NET_TIMEOUT": "2s", "EXAMPLE_NET_CURL_TIMEOUT": "3s"},
+		expect: "3s",
+		found:  true,
+	}, {
+		name:   "empty value",
+		vars:   map[string]string{"EXAMPLE_TIMEOUT": "1s", "EXAMPLE_NET_CURL_TIMEOUT": ""},
+		expect: "",
+		found:  true,
+	}}
+	for _, tc := range lookupCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vars = tc.vars
+			require.NoError(t, disp.Main(context.Background(), []string{"net", "curl"}))
+			value, found := env.Lookup("TIMEOUT")
+			assert.Equal(t, tc.expect, value)
+			assert.Equal(t, tc.found, found)
+		})
+	}
+
+	t.Run("typed lookups", func(t *testing.T) {
+		vars = map[string]string{
+			"EXAMPLE_NET_CURL_TIMEOUT": "3s",
+			"EXAMPLE_VERBOSE":          "true",
+			"EXAMPLE_NET_RETRIES":      "5",
+			"EXAMPLE_NET_CURL_BROKEN":  "nope",
+		}
+		require.NoError(t, disp.Main(context.Background(), []string{"net", "curl"}))
+
+		timeout, err := env.Duration("TIMEOUT", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, 3*time.Second, timeout)
+
+		verbose, err := env.Bool("VERBOSE", false)
+		require.NoError(t, err)
+		assert.True(t, verbose)
+
+		retries, err := env.Int("RETRIES", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 5, retries)
+
+		assert.Equal(t, "fallback", env.String("MISSING", "fallback"))
+		assert.Equal(t, "3s", env.String("TIMEOUT", "fallback"))
+
+		broken, err := env.Int("BROKEN", 7)
+		assert.ErrorContains(t, err, "invalid value for EXAMPLE_NET_CURL_BROKEN: ")
+		assert.Equal(t, 7, broken)
+
+		missing, err := env.Duration("MISSING", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, missing)
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var stdout bytes.Buffer
+		net.Stdout = &stdout
+		require.NoError(t, disp.Main(context.Background(), []string{"net", "--help"}))
+		assert.Contains(t, stdout.String(), "\nEnvironment\n"+
+			"\n    EXAMPLE_NET_PROXY\n\n        Proxy URL to use.\n"+
+			"\n    EXAMPLE_NET_CURL_TIMEOUT\n\n        Timeout for transferring URLs.\n")
+	})
+
+	t.Run("export", func(t *testing.T) {
+		tree := disp.ExportCommandTree()
+		assert.Equal(t, []EnvVarInfo{{
+			Description: []string{"Default timeout for all commands."},
+			Name:        "EXAMPLE_TIMEOUT",
+		}}, tree.Environment)
+		assert.Equal(t, "EXAMPLE_NET_PROXY", tree.findCommand("net").Environment[0].Name)
+		assert.Equal(t, "EXAMPLE_NET_CURL_TIMEOUT", tree.findCommand("net").findCommand("curl").Environment[0].Name)
+	})
+}
+
+func TestEnvironmentFromContextEmpty(t *testing.T) {
+	env := EnvironmentFromContext(context.Background())
+	_, found := env.Lookup("TIMEOUT")
+	assert.False(t, found)
+	assert.Equal(t, "fallback", env.String("TIMEOUT", "fallback"))
+}
+
+func TestEnvVarPrefix(t *testing.T) {
+	assert.Equal(t, "EXAMPLE_NET_CURL", envVarPrefix([]string{"example", "net", "curl"}))
+	assert.Equal(t, "MY_TOOL_V2", envVarPrefix([]string{"my-tool", "v2"}))
+}
+
+func TestDispatcherCommandMustAddCommandEnvVarPanicsForUnknownCommand(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	assert.Panics(t, func() {
+		disp.MustAddCommandEnvVar("nonexistent", "TIMEOUT")
+	})
+}
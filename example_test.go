@@ -4,7 +4,10 @@ package vclip_test
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/bassosimone/vclip"
 	"github.com/bassosimone/vflag"
@@ -713,3 +716,387 @@ func Example_dispatcherCommandUsageHelpWithInvalidSubcommand() {
 	// example help: command not found: nope
 	// example help: try `example help --help' for more help.
 }
+
+// This example shows the suggestions emitted for a mistyped command
+func Example_dispatcherCommandUsageWithMistypedCommand() {
+	// create and init the dispatcher command
+	disp := vclip.NewDispatcherCommand("example", vflag.ExitOnError)
+	disp.AddDescription("Dispatcher for network commands.")
+
+	// add two commands faking curl and dig
+	disp.AddCommand(
+		"curl",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to transfer URLs.",
+	)
+	disp.AddCommand(
+		"dig",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to query DNS servers.",
+	)
+
+	// Override Exit to transform it into a panic: using ExitOnError for a subcommand
+	// eventually causes `disp.Exit(0)` to be invoked after printing help
+	disp.Exit = func(status int) {
+		panic("mocked exit invocation")
+	}
+
+	// Handle the panic by caused by Exit by simply ignoring it
+	defer func() { recover() }()
+
+	// Redirect the stderr to the stdout so that we can capture it
+	disp.Stderr = os.Stdout
+
+	// a background context is sufficient for this example
+	ctx := context.Background()
+
+	// Invoke with `crul` so that we fail and get a suggestion
+	disp.Main(ctx, []string{"crul"})
+
+	// Output:
+	// example: command not found: crul (did you mean `curl'?)
+	// example: use `example --help' to see the available commands
+}
+
+// This example shows how deprecated commands appear in the help.
+func Example_dispatcherCommandUsageWithDeprecatedCommand() {
+	// create and init the dispatcher command
+	disp := vclip.NewDispatcherCommand("example", vflag.ExitOnError)
+	disp.AddDescription("Dispatcher for network commands.")
+
+	// add a command faking curl
+	disp.AddCommand(
+		"curl",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to transfer URLs.",
+	)
+
+	// add the same command with its old name and deprecate it
+	disp.AddCommand(
+		"fetch",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to transfer URLs.",
+	)
+	disp.MustDeprecateCommand("fetch", "curl", "v2.0.0")
+
+	// a background context is sufficient for this example
+	ctx := context.Background()
+
+	// Invoke with `--help` so that we print the help
+	disp.Main(ctx, []string{"--help"})
+
+	// Output:
+	// Usage
+	//
+	//     example <command> [args...]
+	//
+	// Description
+	//
+	//     Dispatcher for network commands.
+	//
+	// Commands
+	//
+	//     curl
+	//
+	//         Utility to transfer URLs.
+	//
+	//     -h, --help, help
+	//
+	//         Show help about this command or about a subcommand.
+	//
+	// Deprecated
+	//
+	//     fetch
+	//
+	//         Utility to transfer URLs.
+	//
+	//         This command is deprecated and will be removed in v2.0.0; use
+	//         `curl' instead.
+	//
+	// Hints
+	//
+	//     Use `example <command> --help' to get command-specific help.
+	//
+	//     Append `--help' or `-h' to any command line failing with usage
+	//     errors to hide the error and obtain contextual help.
+}
+
+// This example shows how command groups appear in the help.
+func Example_dispatcherCommandUsageWithCommandGroups() {
+	// create and init the dispatcher command
+	disp := vclip.NewDispatcherCommand("example", vflag.ExitOnError)
+	disp.AddDescription("Dispatcher for network commands.")
+	disp.AddVersionHandlers("v0.1.0")
+
+	// add commands faking curl, dig, and ping
+	disp.AddCommand(
+		"curl",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to transfer URLs.",
+	)
+	disp.AddCommand(
+		"dig",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to query DNS servers.",
+	)
+	disp.AddCommand(
+		"ping",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to check whether a host is reachable.",
+	)
+
+	// assign the commands to groups
+	disp.AddCommandGroup("Network")
+	disp.AddCommandGroup("Diagnostics")
+	disp.MustSetCommandGroup("curl", "Network")
+	disp.MustSetCommandGroup("dig", "Network")
+	disp.MustSetCommandGroup("ping", "Diagnostics")
+
+	// a background context is sufficient for this example
+	ctx := context.Background()
+
+	// Invoke with `--help` so that we print the help
+	disp.Main(ctx, []string{"--help"})
+
+	// Output:
+	// Usage
+	//
+	//     example <command> [args...]
+	//
+	// Description
+	//
+	//     Dispatcher for network commands.
+	//
+	// Network
+	//
+	//     curl
+	//
+	//         Utility to transfer URLs.
+	//
+	//     dig
+	//
+	//         Utility to query DNS servers.
+	//
+	// Diagnostics
+	//
+	//     ping
+	//
+	//         Utility to check whether a host is reachable.
+	//
+	// Other commands
+	//
+	//     -h, --help, help
+	//
+	//         Show help about this command or about a subcommand.
+	//
+	//     --version, version
+	//
+	//         Show the version number and exit.
+	//
+	// Hints
+	//
+	//     Use `example <command> --help' to get command-specific help.
+	//
+	//     Append `--help' or `-h' to any command line failing with usage
+	//     errors to hide the error and obtain contextual help.
+}
+
+// This example shows how to enumerate the commands reachable from a dispatcher.
+func Example_walk() {
+	// create the nested dispatcher command
+	net := vclip.NewDispatcherCommand("example net", vflag.ExitOnError)
+	net.AddCommand(
+		"curl",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			return nil
+		}),
+		"Utility to transfer URLs.",
+	)
+	net.MustAddCommandAlias("curl", "c")
+
+	// create the top-level dispatcher command
+	disp := vclip.NewDispatcherCommand("example", vflag.ExitOnError)
+	disp.AddDescription("Dispatcher for network commands.")
+	disp.AddCommand("net", net, "Network commands.")
+
+	// walk the tree skipping the built-in help command
+	err := vclip.Walk(disp, func(node *vclip.CommandNode) error {
+		if node.Name() == "help" {
+			return vclip.SkipCommand
+		}
+		fmt.Printf("%s: %s %v\n", strings.Join(node.Path, " "), node.Summary(), node.Aliases)
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// example: Dispatcher for network commands. []
+	// example net: Network commands. []
+	// example net curl: Utility to transfer URLs. [c]
+}
+
+// This example shows the usage printed when the dispatcher declares global flags.
+func Example_dispatcherCommandUsageWithGlobalFlags() {
+	// create and init the dispatcher command
+	disp := vclip.NewDispatcherCommand("example", vflag.ExitOnError)
+	disp.AddDescription("Dispatcher for network commands.")
+
+	// declare the global flags
+	disp.AddGlobalBoolFlag('v', "verbose", "Enable verbose logging.")
+	disp.AddGlobalStringFlag('c', "config", "FILE", "Read the configuration from FILE.")
+
+	// add a command printing the value of the global flags
+	disp.AddCommand(
+		"curl",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			values := vclip.GlobalFlagValuesFromContext(ctx)
+			fmt.Printf("verbose=%v config=%q args=%v\n", values.Bool("verbose"), values.String("config"), args)
+			return nil
+		}),
+		"Utility to transfer URLs.",
+	)
+
+	// a background context is sufficient for this example
+	ctx := context.Background()
+
+	// Invoke with global flags preceding the command name
+	disp.Main(ctx, []string{"-v", "--config", "x.json", "curl", "-v", "https://example.com/"})
+
+	// Invoke with `--help` so that we print the help
+	disp.Main(ctx, []string{"--help"})
+
+	// Output:
+	// verbose=true config="x.json" args=[-v https://example.com/]
+	//
+	// Usage
+	//
+	//     example [options] <command> [args...]
+	//
+	// Description
+	//
+	//     Dispatcher for network commands.
+	//
+	// Global options
+	//
+	//     -v, --verbose
+	//
+	//         Enable verbose logging.
+	//
+	//     -c, --config FILE
+	//
+	//         Read the configuration from FILE.
+	//
+	// Commands
+	//
+	//     curl
+	//
+	//         Utility to transfer URLs.
+	//
+	//     -h, --help, help
+	//
+	//         Show help about this command or about a subcommand.
+	//
+	// Hints
+	//
+	//     Use `example <command> --help' to get command-specific help.
+	//
+	//     Append `--help' or `-h' to any command line failing with usage
+	//     errors to hide the error and obtain contextual help.
+	//
+	//     Global options must appear before the command name.
+}
+
+// This example shows the usage printed when declaring environment variables.
+func Example_dispatcherCommandUsageWithEnvironment() {
+	// create and init the dispatcher command
+	disp := vclip.NewDispatcherCommand("example", vflag.ExitOnError)
+	disp.AddDescription("Dispatcher for network commands.")
+	disp.AddEnvVar("TIMEOUT", "Default timeout for all commands.")
+
+	// add a command reading the environment
+	disp.AddCommand(
+		"curl",
+		vclip.CommandFunc(func(ctx context.Context, args []string) error {
+			env := vclip.EnvironmentFromContext(ctx)
+			timeout, err := env.Duration("TIMEOUT", 10*time.Second)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("timeout=%v\n", timeout)
+			return nil
+		}),
+		"Utility to transfer URLs.",
+	)
+	disp.MustAddCommandEnvVar("curl", "TIMEOUT", "Timeout for transferring URLs.")
+
+	// use a fake environment for this example
+	disp.LookupEnv = func(key string) (string, bool) {
+		return map[string]string{"EXAMPLE_TIMEOUT": "5s"}[key], key == "EXAMPLE_TIMEOUT"
+	}
+
+	// a background context is sufficient for this example
+	ctx := context.Background()
+
+	// Invoke the command to show that it sees the environment
+	disp.Main(ctx, []string{"curl"})
+
+	// Invoke with `--help` so that we print the help
+	disp.Main(ctx, []string{"--help"})
+
+	// Output:
+	// timeout=5s
+	//
+	// Usage
+	//
+	//     example <command> [args...]
+	//
+	// Description
+	//
+	//     Dispatcher for network commands.
+	//
+	// Commands
+	//
+	//     curl
+	//
+	//         Utility to transfer URLs.
+	//
+	//     -h, --help, help
+	//
+	//         Show help about this command or about a subcommand.
+	//
+	// Environment
+	//
+	//     EXAMPLE_TIMEOUT
+	//
+	//         Default timeout for all commands.
+	//
+	//     EXAMPLE_CURL_TIMEOUT
+	//
+	//         Timeout for transferring URLs.
+	//
+	// Hints
+	//
+	//     Use `example <command> --help' to get command-specific help.
+	//
+	//     Append `--help' or `-h' to any command line failing with usage
+	//     errors to hide the error and obtain contextual help.
+	//
+	//     The environment variables of a command override the ones with the
+	//     same name of its parent commands.
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExitCoder is an error carrying the exit code that the program should use.
+//
+// With the [vflag.ExitOnError] policy, [*DispatcherCommand] exits using the exit
+// code of the first error implementing this interface that we find using [errors.As].
+// The same applies to [*RootCommand]. Exit codes lower than 1 become 1, such that
+// the program never exits successfully after an error. We print the error before
+// exiting, unless such an error implements [QuietExitCoder] and asks not to. Such
+// an exit code wins over the exit code 2 we otherwise use for [ErrCommandNotFound]
+// and [ErrAmbiguousCommand], even when the error wraps them.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// QuietExitCoder is an optional interface that an [ExitCoder] may implement to
+// indicate whether we should exit without printing the error (e.g., because the
+// command has already printed a suitable error message).
+type QuietExitCoder interface {
+	ExitCoder
+	QuietExit() bool
+}
+
+// ExitError is an [ExitCoder] wrapping an error.
+//
+// Use [errors.As] to access the fields.
+type ExitError struct {
+	// Code is the exit code.
+	Code int
+
+	// Err is the optional underlying error.
+	Err error
+
+	// Quiet indicates that we should not print the error because
+	// the command has already printed a suitable error message.
+	Quiet bool
+}
+
+var _ QuietExitCoder = &ExitError{}
+
+// Error implements error.
+func (err *ExitError) Error() string {
+	if err.Err == nil {
+		return fmt.Sprintf("exit status %d", err.Code)
+	}
+	return err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *ExitError) Unwrap() error {
+	return err.Err
+}
+
+// ExitCode implements [ExitCoder].
+func (err *ExitError) ExitCode() int {
+	return err.Code
+}
+
+// QuietExit implements [QuietExitCoder].
+func (err *ExitError) QuietExit() bool {
+	return err.Quiet
+}
+
+// exitStatus returns the exit code to use for the given error along with
+// whether we should print the error, according to the rules explained
+// in the documentation of [ExitCoder] and [QuietExitCoder].
+func exitStatus(err error) (int, bool) {
+	var coder ExitCoder
+	if !errors.As(err, &coder) {
+		return 1, true
+	}
+	quiet := false
+	if quietCoder, ok := coder.(QuietExitCoder); ok {
+		quiet = quietCoder.QuietExit()
+	}
+	return max(coder.ExitCode(), 1), !quiet
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitError(t *testing.T) {
+	err := &ExitError{Code: 3, Err: errors.New("not found on server")}
+	assert.Equal(t, "not found on server", err.Error())
+	assert.Equal(t, 3, err.ExitCode())
+	assert.Equal(t, "exit status 4", (&ExitError{Code: 4}).Error())
+}
+
+// testQuietExitCoder is a [QuietExitCoder] other than [*ExitError].
+type testQuietExitCoder struct{}
+
+func (testQuietExitCoder) Error() string   { return "already printed" }
+func (testQuietExitCoder) ExitCode() int   { return 6 }
+func (testQuietExitCoder) QuietExit() bool { return true }
+
+func TestExitStatus(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		code      int
+		printable bool
+	}{{
+		name:      "plain error",
+		err:       errors.New("boom"),
+		code:      1,
+		printable: true,
+	}, {
+		name:      "exit error",
+		err:       &ExitError{Code: 3, Err: errors.New("not found on server")},
+		code:      3,
+		printable: true,
+	}, {
+		name:      "wrapped quiet exit error",
+		err:       fmt.Errorf("partial failure: %w", &ExitError{Code: 4, Quiet: true}),
+		code:      4,
+		printable: false,
+	}, {
+		name:      "custom quiet exit coder",
+		err:       fmt.Errorf("wrapped: %w", testQuietExitCoder{}),
+		code:      6,
+		printable: false,
+	}, {
+		name:      "outermost exit coder wins",
+		err:       &ExitError{Code: 3, Err: testQuietExitCoder{}},
+		code:      3,
+		printable: true,
+	}, {
+		name:      "exit code lower than one",
+		err:       &ExitError{Code: 0, Err: errors.New("boom")},
+		code:      1,
+		printable: true,
+	}, {
+		name:      "plugin error",
+		err:       &PluginError{Err: errors.New("exec format error"), Path: "/usr/bin/example-foo"},
+		code:      1,
+		printable: true,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, printable := exitStatus(tc.err)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.printable, printable)
+		})
+	}
+}
+
+func TestDispatcherCommandMainExitOnErrorExitCoder(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		stderr string
+	}{{
+		name:   "exit error",
+		err:    &ExitError{Code: 3, Err: errors.New("not found on server")},
+		status: 3,
+		stderr: "not found on server\n",
+	}, {
+		name:   "quiet exit error",
+		err:    &ExitError{Code: 4, Err: errors.New("partial failure"), Quiet: true},
+		status: 4,
+		stderr: "",
+	}, {
+		name:   "exit error wrapping ErrCommandNotFound",
+		err:    &ExitError{Code: 3, Err: fmt.Errorf("lookup: %w", ErrCommandNotFound)},
+		status: 3,
+		stderr: "lookup: command not found\n",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the nested dispatcher returns the error to the outer one
+			net := NewDispatcherCommand("net", vflag.ContinueOnError)
+			net.AddCommand("curl", CommandFunc(func(ctx context.Context, args []string) error {
+				return tc.err
+			}))
+			disp := NewDispatcherCommand("example", vflag.ExitOnError)
+			disp.AddCommand("net", net)
+
+			status, _, stderr := runMainExpectExit(t, disp, []string{"net", "curl"})
+			assert.Equal(t, tc.status, status)
+			assert.Equal(t, tc.stderr, stderr)
+		})
+	}
+}
+
+func TestDispatcherCommandMainExitOnErrorExitCoderNested(t *testing.T) {
+	// the nested dispatcher exits on its own
+	var (
+		stderr bytes.Buffer
+		status int
+	)
+	net := NewDispatcherCommand("net", vflag.ExitOnError)
+	net.Exit = func(code int) {
+		status = code
+		panic(exitPanic{status: code})
+	}
+	net.Stderr = &stderr
+	net.AddCommand("curl", CommandFunc(func(ctx context.Context, args []string) error {
+		return fmt.Errorf("curl: %w", &ExitError{Code: 5, Err: errors.New("timeout")})
+	}))
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("net", net)
+
+	assert.PanicsWithValue(t, exitPanic{status: 5}, func() {
+		_ = disp.Main(context.Background(), []string{"net", "curl"})
+	})
+	assert.Equal(t, 5, status)
+	assert.Equal(t, "curl: timeout\n", stderr.String())
+}
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"slices"
+
+	"github.com/bassosimone/runtimex"
+)
+
+// CommandInfo is the machine-readable description of a command.
+//
+// Obtain using [*DispatcherCommand.ExportCommandTree].
+type CommandInfo struct {
+	// Aliases contains the aliases of the command within its parent.
+	Aliases []string `json:"aliases"`
+
+	// Commands contains the subcommands when the command is a [*DispatcherCommand].
+	Commands []*CommandInfo `json:"commands,omitempty"`
+
+	// Deprecated, when not nil, indicates that the command is deprecated.
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+
+	// Description contains the description paragraphs.
+	Description []string `json:"description"`
+
+	// Dispatcher indicates whether the command is a [*DispatcherCommand].
+	Dispatcher bool `json:"dispatcher"`
+
+	// Environment contains the environment variables declared for the command.
+	Environment []EnvVarInfo `json:"environment,omitempty"`
+
+	// Flags contains the flags of commands implementing [FlagDescriber]
+	// or the global flags of a [*DispatcherCommand].
+	Flags []FlagInfo `json:"flags,omitempty"`
+
+	// Group is the optional name of the group the command belongs to.
+	Group string `json:"group,omitempty"`
+
+	// Hidden indicates that the command is hidden.
+	Hidden bool `json:"hidden"`
+
+	// Name is the command name within its parent.
+	Name string `json:"name"`
+
+	// Path contains the full command path (e.g., `example net curl`).
+	Path []string `json:"path"`
+
+	// Shortcuts maps the names of the shortcuts expanding to the command
+	// to the arguments following the command name. See [CommandNode].
+	Shortcuts map[string][]string `json:"shortcuts,omitempty"`
+}
+
+// EnvVarInfo is the machine-readable description of an environment variable.
+type EnvVarInfo struct {
+	// Description contains the description paragraphs.
+	Description []string `json:"description"`
+
+	// Name is the full variable name (e.g., `EXAMPLE_NET_CURL_TIMEOUT`).
+	Name string `json:"name"`
+}
+
+// FlagInfo is the machine-readable description of a command flag.
+type FlagInfo struct {
+	// ArgName is the name of the flag argument (e.g., `FILE`) or an
+	// empty string when the flag does not take any argument.
+	ArgName string `json:"arg_name,omitempty"`
+
+	// Description contains the description paragraphs.
+	Description []string `json:"description"`
+
+	// Long is the long flag name without the leading dashes (e.g., `location`).
+	Long string `json:"long,omitempty"`
+
+	// Short is the short flag name without the leading dash (e.g., `L`).
+	Short string `json:"short,omitempty"`
+}
+
+// FlagDescriber is an optional interface that a [Command] may implement
+// to include its flags into [*DispatcherCommand.ExportCommandTree].
+type FlagDescriber interface {
+	DescribeFlags() []FlagInfo
+}
+
+// ExportCommandTree returns the machine-readable description of the
+// [*DispatcherCommand] and of all the commands reachable from it.
+//
+// Unlike the help and the generated docs, the returned tree
+// also includes the hidden commands, marked as such.
+func (c *DispatcherCommand) ExportCommandTree() *CommandInfo {
+	var root *CommandInfo
+	infos := map[*CommandNode]*CommandInfo{}
+	runtimex.PanicOnError0(Walk(c, func(node *CommandNode) error {
+		info := &CommandInfo{
+			Aliases:     node.Aliases,
+			Deprecated:  node.Command.Deprecated,
+			Description: append([]string{}, node.Description()...),
+			Dispatcher:  node.Dispatcher != nil,
+			Group:       node.Command.Group,
+			Hidden:      node.Command.Hidden,
+			Name:        node.Name(),
+			Path:        node.Path,
+		}
+		if len(node.Shortcuts) > 0 {
+			info.Shortcuts = node.Shortcuts
+		}
+		if describer, ok := node.Command.Cmd.(FlagDescriber); ok {
+			info.Flags = describer.DescribeFlags()
+		}
+		envVars := slices.Clone(node.Command.EnvVars)
+		if node.Dispatcher != nil {
+			envVars = append(envVars, node.Dispatcher.EnvVars...)
+		}
+		for _, envVar := range envVars {
+			info.Environment = append(info.Environment, EnvVarInfo{
+				Description: envVar.Descr,
+				Name:        envVarPrefix(node.Path) + "_" + envVar.Name,
+			})
+		}
+		if node.Dispatcher != nil {
+			for _, flag := range node.Dispatcher.GlobalFlags {
+				var short string
+				if flag.Short != 0 {
+					short = string(flag.Short)
+				}
+				info.Flags = append(info.Flags, FlagInfo{
+					ArgName:     flag.ArgName,
+					Description: flag.Descr,
+					Long:        flag.Long,
+					Short:       short,
+				})
+			}
+		}
+		infos[node] = info
+		if node.Parent == nil {
+			root = info
+			return nil
+		}
+		parent := infos[node.Parent]
+		parent.Commands = append(parent.Commands, info)
+		return nil
+	}))
+	return root
+}
+
+// findCommand returns the direct subcommand with the given name or nil.
+func (ci *CommandInfo) findCommand(name string) *CommandInfo {
+	for _, child := range ci.Commands {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
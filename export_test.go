@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFlagDescriberCommand struct {
+	testCommand
+}
+
+func (tc *testFlagDescriberCommand) DescribeFlags() []FlagInfo {
+	return []FlagInfo{
+		{Description: []string{"Follow redirects."}, Long: "location", Short: "L"},
+		{ArgName: "FILE", Description: []string{"Write output to FILE."}, Long: "output", Short: "o"},
+	}
+}
+
+func TestDispatcherCommandExportCommandTree(t *testing.T) {
+	disp := newDocsTestDispatcher()
+	disp.AddCommand("wget", &testFlagDescriberCommand{}, "Download files.")
+	disp.AddCommandGroup("Transfer")
+	disp.MustSetCommandGroup("wget", "Transfer")
+	tree := disp.ExportCommandTree()
+
+	assert.Equal(t, "example", tree.Name)
+	assert.Equal(t, []string{"example"}, tree.Path)
+	assert.True(t, tree.Dispatcher)
+	assert.Equal(t, []string{"Dispatcher for network commands."}, tree.Description)
+
+	var names []string
+	for _, child := range tree.Commands {
+		names = append(names, child.Name)
+	}
+	assert.Equal(t, []string{"debug", "fetch", "help", "net", "wget"}, names)
+
+	debug := tree.findCommand("debug")
+	require.NotNil(t, debug)
+	assert.True(t, debug.Hidden)
+
+	fetch := tree.findCommand("fetch")
+	require.NotNil(t, fetch)
+	assert.Equal(t, &Deprecation{RemovalVersion: "v2.0.0", Replacement: "net curl"}, fetch.Deprecated)
+
+	help := tree.findCommand("help")
+	require.NotNil(t, help)
+	assert.Equal(t, []string{"-h", "--help"}, help.Aliases)
+
+	curl := tree.findCommand("net").findCommand("curl")
+	require.NotNil(t, curl)
+	assert.Equal(t, &CommandInfo{
+		Aliases: []string{"c"},
+		Description: []string{
+			"Utility to transfer URLs.",
+			"    curl -fsSL https://example.com/",
+			"Follows redirects.",
+		},
+		Name:      "curl",
+		Path:      []string{"example", "net", "curl"},
+		Shortcuts: map[string][]string{"get": {"-fsSL"}},
+	}, curl)
+
+	wget := tree.findCommand("wget")
+	require.NotNil(t, wget)
+	assert.Equal(t, "Transfer", wget.Group)
+	assert.Len(t, wget.Flags, 2)
+
+	assert.Nil(t, tree.findCommand("nonexistent"))
+}
+
+func TestDispatcherCommandHelpMainJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	disp := newDocsTestDispatcher()
+	disp.Stderr = &stderr
+	disp.Stdout = &stdout
+	disp.AddCommand("wget", &testFlagDescriberCommand{}, "Download files.")
+
+	t.Run("command tree", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{"help", "--json"})
+		require.NoError(t, err)
+
+		var tree CommandInfo
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &tree))
+		assert.Equal(t, disp.ExportCommandTree(), &tree)
+		assert.Contains(t, stdout.String(), "\n  \"name\": \"example\",\n")
+		assert.Contains(t, stdout.String(), "\"arg_name\": \"FILE\"")
+		assert.Contains(t, stdout.String(), "\"removal_version\": \"v2.0.0\"")
+	})
+
+	t.Run("subcommand", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{"help", "--json", "net"})
+		require.NoError(t, err)
+
+		var tree CommandInfo
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &tree))
+		assert.Equal(t, []string{"example", "net"}, tree.Path)
+		assert.Len(t, tree.Commands, 2)
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		stdout.Reset()
+		stderr.Reset()
+		err := disp.Main(context.Background(), []string{"help", "--json", "nope"})
+		assert.ErrorIs(t, err, ErrCommandNotFound)
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, stderr.String(), "command not found: nope")
+	})
+
+	t.Run("command path", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{"help", "--json", "net", "c"})
+		require.NoError(t, err)
+
+		var tree CommandInfo
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &tree))
+		assert.Equal(t, []string{"example", "net", "curl"}, tree.Path)
+	})
+}
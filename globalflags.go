@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bassosimone/vflag"
+)
+
+// GlobalFlag is a global flag declared using [*DispatcherCommand.AddGlobalBoolFlag]
+// or [*DispatcherCommand.AddGlobalStringFlag].
+type GlobalFlag struct {
+	// ArgName is the name of the flag argument (e.g., `FILE`) or an
+	// empty string when the flag is a boolean flag.
+	ArgName string
+
+	// Descr contains the flag description.
+	Descr []string
+
+	// Long is the long flag name without the leading dashes (e.g., `verbose`).
+	Long string
+
+	// Short is the short flag name or zero when there is no short flag name.
+	Short rune
+}
+
+// names returns the flag names with leading dashes (e.g., `-v, --verbose`).
+func (gf *GlobalFlag) names() string {
+	var names []string
+	if gf.Short != 0 {
+		names = append(names, fmt.Sprintf("-%c", gf.Short))
+	}
+	if gf.Long != "" {
+		names = append(names, fmt.Sprintf("--%s", gf.Long))
+	}
+	return strings.Join(names, ", ")
+}
+
+// key returns the name to use with [*GlobalFlagValues] methods.
+func (gf *GlobalFlag) key() string {
+	if gf.Long != "" {
+		return gf.Long
+	}
+	return string(gf.Short)
+}
+
+// AddGlobalBoolFlag declares a boolean global flag with the given short name (zero
+// for none), long name (empty for none) and description.
+//
+// Global flags are opt-in and must appear before the command name (e.g., `example
+// --verbose net curl`). We only parse the leading arguments matching declared global
+// flags and stop at the first non-matching argument, so that the arguments of the
+// commands remain untouched. Commands read the parsed values using [GlobalFlagValuesFromContext].
+func (c *DispatcherCommand) AddGlobalBoolFlag(short rune, long string, descr ...string) {
+	c.GlobalFlags = append(c.GlobalFlags, GlobalFlag{
+		ArgName: "",
+		Descr:   descr,
+		Long:    long,
+		Short:   short,
+	})
+}
+
+// AddGlobalStringFlag is like [*DispatcherCommand.AddGlobalBoolFlag] but declares
+// a global flag taking the argument described by argName (e.g., `FILE`).
+//
+// The argument is either the next command line argument (e.g., `--config x.json`
+// or `-c x.json`) or follows the long flag name and an equal sign (`--config=x.json`).
+func (c *DispatcherCommand) AddGlobalStringFlag(short rune, long, argName string, descr ...string) {
+	c.GlobalFlags = append(c.GlobalFlags, GlobalFlag{
+		ArgName: argName,
+		Descr:   descr,
+		Long:    long,
+		Short:   short,
+	})
+}
+
+// lookupGlobalFlag returns the index of the global flag matching the given argument,
+// or -1, and whether the argument already contains the flag value (e.g., `--config=x.json`).
+func (c *DispatcherCommand) lookupGlobalFlag(arg string) (int, bool) {
+	for idx, flag := range c.GlobalFlags {
+		switch {
+		case flag.Long != "" && arg == "--"+flag.Long:
+			return idx, false
+		case flag.Long != "" && flag.ArgName != "" && strings.HasPrefix(arg, "--"+flag.Long+"="):
+			return idx, true
+		case flag.Short != 0 && arg == fmt.Sprintf("-%c", flag.Short):
+			return idx, false
+		}
+	}
+	return -1, false
+}
+
+// splitGlobalFlags splits the leading arguments matching the declared global flags,
+// including their values, from the remaining arguments. It also returns the indexes
+// of the global flags that we have seen.
+func (c *DispatcherCommand) splitGlobalFlags(args []string) ([]string, []string, []int) {
+	var seen []int
+	idx := 0
+	for idx < len(args) {
+		flagIdx, hasValue := c.lookupGlobalFlag(args[idx])
+		if flagIdx < 0 {
+			break
+		}
+		seen = append(seen, flagIdx)
+		idx++
+		if c.GlobalFlags[flagIdx].ArgName != "" && !hasValue && idx < len(args) {
+			idx++
+		}
+	}
+	return args[:idx], args[idx:], seen
+}
+
+// missingGlobalFlagValue returns whether the given leading arguments
+// end with a global flag that requires a value that is missing.
+func (c *DispatcherCommand) missingGlobalFlagValue(leading []string) bool {
+	if len(leading) <= 0 {
+		return false
+	}
+	flagIdx, hasValue := c.lookupGlobalFlag(leading[len(leading)-1])
+	return flagIdx >= 0 && c.GlobalFlags[flagIdx].ArgName != "" && !hasValue
+}
+
+// parseGlobalFlags parses the leading global flags and returns a context
+// containing their values along with the remaining arguments.
+func (c *DispatcherCommand) parseGlobalFlags(ctx context.Context, args []string) (context.Context, []string, error) {
+	leading, args, seen := c.splitGlobalFlags(args)
+	if len(leading) <= 0 {
+		return ctx, args, nil
+	}
+
+	// initialize the flag set
+	fset := vflag.NewFlagSet(c.FullName(), c.ErrorHandling)
+	fset.SetMinMaxPositionalArgs(0, 0)
+	fset.Exit = c.Exit
+	fset.Stderr = c.Stderr
+	fset.Stdout = c.Stdout
+	bools := make([]bool, len(c.GlobalFlags))
+	strs := make([]string, len(c.GlobalFlags))
+	for idx, flag := range c.GlobalFlags {
+		if flag.ArgName != "" {
+			fset.StringVar(&strs[idx], flag.Short, flag.Long)
+			continue
+		}
+		fset.BoolVar(&bools[idx], flag.Short, flag.Long)
+	}
+
+	// parse the leading global flags
+	if err := fset.Parse(leading); err != nil {
+		fset.PrintUsageError(c.Stderr, err)
+		return ctx, nil, err
+	}
+
+	// layer the values we have seen on top of the parent values
+	values := &GlobalFlagValues{values: map[string]string{}}
+	for key, value := range GlobalFlagValuesFromContext(ctx).values {
+		values.values[key] = value
+	}
+	for _, idx := range seen {
+		flag := &c.GlobalFlags[idx]
+		if flag.ArgName != "" {
+			values.values[flag.key()] = strs[idx]
+			continue
+		}
+		values.values[flag.key()] = fmt.Sprintf("%v", bools[idx])
+	}
+	return context.WithValue(ctx, globalFlagValuesKey{}, values), args, nil
+}
+
+// globalFlagValuesKey is the context key for [*GlobalFlagValues].
+type globalFlagValuesKey struct{}
+
+// GlobalFlagValues contains the values of the global flags parsed by the
+// [*DispatcherCommand] instances along the command path.
+//
+// Obtain using [GlobalFlagValuesFromContext].
+type GlobalFlagValues struct {
+	values map[string]string
+}
+
+// GlobalFlagValuesFromContext returns the [*GlobalFlagValues] stored in the
+// context. When a nested [*DispatcherCommand] declares a global flag with the
+// same name of a parent global flag, the nested value wins.
+//
+// The return value is never nil, yet it is empty without global flags.
+func GlobalFlagValuesFromContext(ctx context.Context) *GlobalFlagValues {
+	if values, ok := ctx.Value(globalFlagValuesKey{}).(*GlobalFlagValues); ok {
+		return values
+	}
+	return &GlobalFlagValues{values: map[string]string{}}
+}
+
+// Lookup returns the value of the given global flag and whether the flag was
+// present in the command line. The name is the long flag name or, when there
+// is no long flag name, the short flag name.
+func (gfv *GlobalFlagValues) Lookup(name string) (string, bool) {
+	value, found := gfv.values[name]
+	return value, found
+}
+
+// Bool returns whether the given boolean global flag was present.
+func (gfv *GlobalFlagValues) Bool(name string) bool {
+	value, _ := gfv.Lookup(name)
+	return value == "true"
+}
+
+// String returns the value of the given string global flag or an empty string.
+func (gfv *GlobalFlagValues) String(name string) string {
+	value, _ := gfv.Lookup(name)
+	return value
+}
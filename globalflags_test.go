@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherCommandGlobalFlags(t *testing.T) {
+	var (
+		curlArgs []string
+		values   *GlobalFlagValues
+	)
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddGlobalStringFlag(0, "proxy", "URL", "Use the given proxy.")
+	net.AddGlobalStringFlag('c', "config", "FILE", "Override the configuration file.")
+	net.AddCommand("curl", CommandFunc(func(ctx context.Context, args []string) error {
+		curlArgs, values = args, GlobalFlagValuesFromContext(ctx)
+		return nil
+	}), "Utility to transfer URLs.")
+
+	var stdout, stderr bytes.Buffer
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.Stderr = &stderr
+	disp.Stdout = &stdout
+	disp.AddGlobalBoolFlag('v', "verbose", "Enable verbose logging.")
+	disp.AddGlobalStringFlag('c', "config", "FILE", "Read the configuration from FILE.")
+	disp.AddCommand("net", net, "Network commands.")
+
+	testCases := []struct {
+		name    string
+		args    []string
+		verbose bool
+		config  string
+		proxy   string
+		curl    []string
+	}{{
+		name: "no global flags",
+		args: []string{"net", "curl", "-v", "https://example.com/"},
+		curl: []string{"-v", "https://example.com/"},
+	}, {
+		name:    "long flags",
+		args:    []string{"--verbose", "--config", "x.json", "net", "curl", "-v"},
+		verbose: true,
+		config:  "x.json",
+		curl:    []string{"-v"},
+	}, {
+		name:    "short flags and equal sign",
+		args:    []string{"-v", "--config=x.json", "net", "--proxy", "http://127.0.0.1:8080/", "curl"},
+		verbose: true,
+		config:  "x.json",
+		proxy:   "http://127.0.0.1:8080/",
+		curl:    []string{},
+	}, {
+		name:   "nested value wins",
+		args:   []string{"-c", "x.json", "net", "-c", "y.json", "curl", "--config", "z.json"},
+		config: "y.json",
+		curl:   []string{"--config", "z.json"},
+	}, {
+		name:   "global flag value looking like a flag",
+		args:   []string{"--config", "-v", "net", "curl"},
+		config: "-v",
+		curl:   []string{},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			curlArgs, values = nil, nil
+			err := disp.Main(context.Background(), tc.args)
+			require.NoError(t, err)
+			assert.Equal(t, tc.curl, curlArgs)
+			assert.Equal(t, tc.verbose, values.Bool("verbose"))
+			assert.Equal(t, tc.config, values.String("config"))
+			assert.Equal(t, tc.proxy, values.String("proxy"))
+		})
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		values = nil
+		err := disp.Main(context.Background(), []string{"--config", "", "net", "curl"})
+		require.NoError(t, err)
+		value, found := values.Lookup("config")
+		assert.True(t, found)
+		assert.Equal(t, "", value)
+		_, found = values.Lookup("verbose")
+		assert.False(t, found)
+	})
+
+	t.Run("missing value", func(t *testing.T) {
+		values = nil
+		stderr.Reset()
+		err := disp.Main(context.Background(), []string{"--verbose", "--config"})
+		require.Error(t, err)
+		assert.Contains(t, stderr.String(), "example: ")
+		assert.Nil(t, values)
+	})
+
+	t.Run("only global flags shows the help", func(t *testing.T) {
+		stdout.Reset()
+		err := disp.Main(context.Background(), []string{"--verbose"})
+		require.NoError(t, err)
+		assert.Contains(t, stdout.String(), "\n    example [options] <command> [args...]\n")
+	})
+
+	t.Run("an unknown flag is a command name", func(t *testing.T) {
+		err := disp.Main(context.Background(), []string{"--verbose", "--quiet", "net", "curl"})
+		var notFound *CommandNotFoundError
+		require.ErrorAs(t, err, &notFound)
+		assert.Equal(t, "--quiet", notFound.Name)
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		completions, err := disp.Complete(context.Background(), []string{"--v"})
+		require.NoError(t, err)
+		assert.Equal(t, []Completion{{Value: "--verbose", Descr: "Enable verbose logging."}}, completions)
+
+		completions, err = disp.Complete(context.Background(), []string{"-v", "--config", "x.json", "n"})
+		require.NoError(t, err)
+		assert.Equal(t, []Completion{{Value: "net", Descr: "Network commands."}}, completions)
+
+		completions, err = disp.Complete(context.Background(), []string{"-v", "--config", ""})
+		require.NoError(t, err)
+		assert.Empty(t, completions)
+
+		completions, err = disp.Complete(context.Background(), []string{"-v", "net", "--proxy", "x", "c"})
+		require.NoError(t, err)
+		assert.Equal(t, []Completion{{Value: "curl", Descr: "Utility to transfer URLs."}}, completions)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		tree := disp.ExportCommandTree()
+		assert.Equal(t, []FlagInfo{
+			{Description: []string{"Enable verbose logging."}, Long: "verbose", Short: "v"},
+			{ArgName: "FILE", Description: []string{"Read the configuration from FILE."}, Long: "config", Short: "c"},
+		}, tree.Flags)
+		assert.Equal(t, "proxy", tree.findCommand("net").Flags[0].Long)
+		assert.Equal(t, "", tree.findCommand("net").Flags[0].Short)
+	})
+}
+
+func TestGlobalFlagValuesFromContextEmpty(t *testing.T) {
+	values := GlobalFlagValuesFromContext(context.Background())
+	require.NotNil(t, values)
+	assert.False(t, values.Bool("verbose"))
+	assert.Equal(t, "", values.String("config"))
+}
@@ -8,10 +8,10 @@ require (
 	github.com/bassosimone/textwrap v0.0.0-20260721155105-b5619ff4e449
 	github.com/bassosimone/vflag v0.0.0-20260821094319-8b4cca87cb7a
 	github.com/stretchr/testify v1.12.1
+	go.yaml.in/yaml/v3 v3.0.5
 )
 
 require (
 	github.com/bassosimone/flagparser v0.0.0-20260817131103-382c730be712 // indirect
 	github.com/bassosimone/flagscanner v0.0.0-20260817125829-500b835a9da5 // indirect
-	go.yaml.in/yaml/v3 v3.0.5 // indirect
 )
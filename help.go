@@ -4,19 +4,26 @@ package vclip
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 
+	"github.com/bassosimone/must"
+	"github.com/bassosimone/runtimex"
 	"github.com/bassosimone/vflag"
 )
 
 // helpMain is the main of the help subcommand.
 func (c *DispatcherCommand) helpMain(ctx context.Context, args []string) error {
 	// initialize the flag set
-	fset := vflag.NewFlagSet(fmt.Sprintf("%s help", c.Name), c.ErrorHandling)
+	fset := vflag.NewFlagSet(fmt.Sprintf("%s help", c.FullName()), c.ErrorHandling)
 	fset.UsagePrinter = c.NewHelpSubcommandUsagePrinter()
 	fset.AutoHelp('h', "help", helpFlagDescr)
-	fset.SetMinMaxPositionalArgs(0, 1)
+	fset.SetMinMaxPositionalArgs(0, math.MaxInt)
+	fJSON := false
+	fset.BoolVar(&fJSON, 0, "json")
 	fset.Exit = c.Exit
 	fset.Stderr = c.Stderr
 	fset.Stdout = c.Stdout
@@ -32,19 +39,112 @@ func (c *DispatcherCommand) helpMain(ctx context.Context, args []string) error {
 
 	// check whether the user is requesting help for a subcommand
 	if len(fset.Args()) > 0 {
-		if cmd, ok := c.findCommand(fset.Args()[0]); ok {
-			return cmd.Main(ctx, []string{"--help"})
+		names, cmd, err := c.lookupCommandPath(ctx, fset.Args())
+		if err != nil {
+			// the flag set name already contains our own path
+			printable := err
+			if len(names) <= 0 {
+				printable = withoutPath(err)
+			}
+			fset.PrintUsageError(c.Stderr, printable)
+			return err
 		}
-		err := fmt.Errorf("%w: %s", ErrCommandNotFound, args[0])
-		fset.PrintUsageError(c.Stderr, err)
-		return err
+		if fJSON {
+			tree := c.ExportCommandTree()
+			for _, name := range names {
+				tree = tree.findCommand(name)
+			}
+			return c.printJSON(tree)
+		}
+		return cmd.Main(ctx, []string{"--help"})
+	}
+
+	// print the machine-readable command tree
+	if fJSON {
+		return c.printJSON(c.ExportCommandTree())
 	}
 
 	// print the general overall help
 	return c.printHelp()
 }
 
+// lookupCommandPath resolves the given command path (e.g., `net curl`) through
+// the nested [*DispatcherCommand], honoring aliases, shortcuts, user aliases, and
+// prefix matching at every level, and returns the names of the resolved commands
+// along with the deepest one. When a shortcut or a user alias expands to a command
+// followed by arguments, we resolve the command and ignore the arguments.
+//
+// On failure, it returns the names resolved so far and an error whose Path
+// identifies the [*DispatcherCommand] where the resolution failed.
+func (c *DispatcherCommand) lookupCommandPath(ctx context.Context, args []string) ([]string, DescribedCommand, error) {
+	var (
+		cmd      DescribedCommand
+		names    []string
+		expanded int // number of upcoming args coming from an expansion
+	)
+	for disp := c; len(args) > 0; args = args[1:] {
+		if disp == nil && expanded > 0 {
+			// skip the arguments of the command named by the expansion
+			expanded--
+			continue
+		}
+		if disp == nil {
+			// the previous command is not a dispatcher, so it has no subcommands
+			return names, DescribedCommand{}, &CommandNotFoundError{
+				Args: args[1:],
+				Name: args[0],
+				Path: strings.Join(append(c.fullPath(), names...), " "),
+			}
+		}
+		if expanded > 0 {
+			expanded-- // we do not expand the words of an expansion
+		} else {
+			_ = disp.loadUserAliases(ConfigSectionFromContext(ctx)) // like main, do not fail help because of the config
+			all, err := disp.expandAliases(args)
+			if err != nil {
+				return names, DescribedCommand{}, err
+			}
+			args, expanded = all, len(all)-len(args)
+		}
+		name, child, err := disp.lookupCommand(args)
+		if err != nil {
+			return names, DescribedCommand{}, err
+		}
+		names, cmd = append(names, name), child
+		ctx = withConfigSubsection(ctx, name)
+		disp, _ = child.Cmd.(*DispatcherCommand)
+	}
+	return names, cmd, nil
+}
+
 func (c *DispatcherCommand) printHelp() error {
 	c.UsagePrinter.PrintHelp(c, c.Stdout)
 	return nil
 }
+
+// printJSON prints the given [*CommandInfo] as indented JSON.
+//
+// This method panics on I/O error.
+func (c *DispatcherCommand) printJSON(info *CommandInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	runtimex.PanicOnError0(err) // cannot fail with this data structure
+	must.Fprintf(c.Stdout, "%s\n", data)
+	return nil
+}
+
+// withoutPath returns a copy of the given lookup error without the dispatcher
+// path, which is redundant when the flag set name already contains it.
+func withoutPath(err error) error {
+	switch err := err.(type) {
+	case *CommandNotFoundError:
+		unprefixed := *err
+		unprefixed.Path = ""
+		return &unprefixed
+	case *AmbiguousCommandError:
+		unprefixed := *err
+		unprefixed.Path = ""
+		return &unprefixed
+	default:
+		return err
+	}
+}
@@ -13,14 +13,17 @@ import "context"
 // 1. we wrap the [context.Context] using [signal.NotifyContext] so that
 // interruptions such as `^C` interrupt the command execution.
 //
-// 2. we use [runtimex.LogFatalOnError0] to ensure that the error
-// returned by the command is logged and leads to exiting.
+// 2. when the command fails with an [ExitCoder], we print the error,
+// unless it is a [QuietExitCoder] asking not to, and we exit using
+// its exit code.
+//
+// 3. otherwise, we use [runtimex.LogFatalOnError0] to ensure that the
+// error returned by the command is logged and leads to exiting.
 //
 // The args MUST NOT contain the program name (i.e. use `os.Args[1:]`).
 //
-// Use [*RootCommand] when you need to override the unconditional
-// [runtimex.LogFatalOnError0] invocation executed by this function
-// in case the underlying command fails.
+// Use [*RootCommand] when you need to override how we print the
+// error and exit in case the underlying command fails.
 func Main(ctx context.Context, cmd Command, args []string) {
 	NewRootCommand(cmd).Main(ctx, args)
 }
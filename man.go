@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bassosimone/runtimex"
+)
+
+// ManPage is a man page generated by [*ManPageGenerator].
+type ManPage struct {
+	// Content is the man page content using the roff format.
+	Content string
+
+	// Name is the man page name (e.g., `example-curl`).
+	Name string
+
+	// Section is the man page section (e.g., `1`).
+	Section string
+}
+
+// FileName returns the conventional man page file name (e.g., `example-curl.1`).
+func (mp *ManPage) FileName() string {
+	return fmt.Sprintf("%s.%s", mp.Name, mp.Section)
+}
+
+// ManPageGenerator generates man pages for a tree of [*DispatcherCommand].
+//
+// Construct using [NewManPageGenerator].
+type ManPageGenerator struct {
+	// Date is the optional date to include in the page header.
+	//
+	// [NewManPageGenerator] initializes it to an empty string, which
+	// makes the generated man pages reproducible.
+	Date string
+
+	// Manual is the optional title of the manual (e.g., `User Commands`).
+	//
+	// [NewManPageGenerator] initializes it to an empty string.
+	Manual string
+
+	// Section is the man page section.
+	//
+	// [NewManPageGenerator] initializes it to `1`.
+	Section string
+
+	// Source is the optional source of the command (e.g., `example 0.1.0`).
+	//
+	// [NewManPageGenerator] initializes it to an empty string.
+	Source string
+}
+
+// NewManPageGenerator creates a new [*ManPageGenerator].
+func NewManPageGenerator() *ManPageGenerator {
+	return &ManPageGenerator{
+		Date:    "",
+		Manual:  "",
+		Section: "1",
+		Source:  "",
+	}
+}
+
+// Generate generates a man page for the given [*DispatcherCommand] (e.g., `example(1)`)
+// and for each non-hidden command reachable from it (e.g., `example-curl(1)`), except
+// for the built-in commands (e.g., `help`), which we only list in the COMMANDS section.
+//
+// Each man page contains the NAME, SYNOPSIS, DESCRIPTION, COMMANDS (only for
+// dispatchers), ALIASES (only when there are aliases), SHORTCUTS (only when
+// there are shortcuts), and SEE ALSO sections.
+//
+// Description paragraphs starting with 4 spaces are emitted verbatim.
+func (g *ManPageGenerator) Generate(c *DispatcherCommand) []ManPage {
+	var pages []ManPage
+	runtimex.PanicOnError0(Walk(c, func(node *CommandNode) error {
+		if node.Hidden || node.isBuiltin() {
+			return SkipCommand
+		}
+		pages = append(pages, ManPage{
+			Content: g.page(node),
+			Name:    strings.Join(node.Path, "-"),
+			Section: g.Section,
+		})
+		return nil
+	}))
+	return pages
+}
+
+// WriteFiles generates the man pages using Generate and writes
+// them into the given directory, which must already exist.
+func (g *ManPageGenerator) WriteFiles(c *DispatcherCommand, dir string) error {
+	for _, page := range g.Generate(c) {
+		if err := os.WriteFile(filepath.Join(dir, page.FileName()), []byte(page.Content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// page generates the man page for the given node.
+func (g *ManPageGenerator) page(node *CommandNode) string {
+	var sb strings.Builder
+	name := strings.Join(node.Path, "-")
+
+	// .TH
+	fmt.Fprintf(&sb, ".TH %s %s %s %s %s\n", roffEscape(strings.ToUpper(name)), roffEscape(g.Section),
+		roffQuote(g.Date), roffQuote(g.Source), roffQuote(g.Manual))
+
+	// NAME
+	fmt.Fprintf(&sb, ".SH NAME\n")
+	summary, description := node.Summary(), node.Description()
+	if summary != "" {
+		fmt.Fprintf(&sb, "%s \\- %s\n", roffEscape(name), roffEscape(summary))
+	} else {
+		fmt.Fprintf(&sb, "%s\n", roffEscape(name))
+	}
+
+	// SYNOPSIS
+	fmt.Fprintf(&sb, ".SH SYNOPSIS\n")
+	fmt.Fprintf(&sb, ".B %s\n", roffEscape(strings.Join(node.Path, " ")))
+	if node.Dispatcher != nil {
+		fmt.Fprintf(&sb, "\\fIcommand\\fR [\\fIargs\\fR...]\n")
+	} else {
+		fmt.Fprintf(&sb, "[\\fIargs\\fR...]\n")
+	}
+
+	// DESCRIPTION
+	if len(description) > 0 || node.Command.Deprecated != nil {
+		fmt.Fprintf(&sb, ".SH DESCRIPTION\n")
+		for _, paragraph := range description {
+			roffParagraph(&sb, ".PP", paragraph)
+		}
+		if node.Command.Deprecated != nil {
+			roffParagraph(&sb, ".PP", fmt.Sprintf("This command is deprecated%s.", node.Command.Deprecated.details()))
+		}
+	}
+
+	// COMMANDS
+	if node.Dispatcher != nil {
+		fmt.Fprintf(&sb, ".SH COMMANDS\n")
+		sections := node.Dispatcher.commandSections()
+		for _, section := range sections {
+			if len(sections) > 1 {
+				fmt.Fprintf(&sb, ".SS %s\n", roffEscape(section.title))
+			}
+			for _, childName := range section.names {
+				child, aliases := node.Dispatcher.childCommand(childName)
+				var words []string
+				for _, word := range append(slices.Clone(aliases), childName) {
+					words = append(words, fmt.Sprintf("\\fB%s\\fR", roffEscape(word)))
+				}
+				fmt.Fprintf(&sb, ".TP\n%s\n", strings.Join(words, ", "))
+				for idx, paragraph := range child.Descr {
+					if idx <= 0 && !isVerbatimParagraph(paragraph) {
+						fmt.Fprintf(&sb, "%s\n", roffEscape(paragraph))
+						continue
+					}
+					roffParagraph(&sb, ".IP", paragraph)
+				}
+				if child.Deprecated != nil {
+					roffParagraph(&sb, ".IP", fmt.Sprintf("This command is deprecated%s.", child.Deprecated.details()))
+				}
+			}
+		}
+	}
+
+	// ALIASES
+	if len(node.Aliases) > 0 {
+		fmt.Fprintf(&sb, ".SH ALIASES\n")
+		parent := strings.Join(node.Path[:len(node.Path)-1], " ")
+		var aliases []string
+		for _, alias := range node.Aliases {
+			aliases = append(aliases, fmt.Sprintf("\\fB%s %s\\fR", roffEscape(parent), roffEscape(alias)))
+		}
+		fmt.Fprintf(&sb, "%s\n", strings.Join(aliases, ", "))
+	}
+
+	// SHORTCUTS
+	if len(node.Shortcuts) > 0 {
+		fmt.Fprintf(&sb, ".SH SHORTCUTS\n")
+		parent := strings.Join(node.Path[:len(node.Path)-1], " ")
+		for _, shortcut := range slices.Sorted(maps.Keys(node.Shortcuts)) {
+			expansion := joinShellWords(append(slices.Clone(node.Path), node.Shortcuts[shortcut]...))
+			fmt.Fprintf(&sb, ".TP\n\\fB%s %s\\fR\n", roffEscape(parent), roffEscape(shortcut))
+			fmt.Fprintf(&sb, "Shortcut for \\fB%s\\fR.\n", roffEscape(expansion))
+		}
+	}
+
+	// SEE ALSO
+	var related []string
+	if node.Parent != nil {
+		related = append(related, strings.Join(node.Parent.Path, "-"))
+	}
+	if node.Dispatcher != nil {
+		for _, childName := range node.Dispatcher.childNames() {
+			if child, _ := node.Dispatcher.childCommand(childName); !child.Hidden && !node.Dispatcher.isBuiltinCommand(childName) {
+				related = append(related, name+"-"+childName)
+			}
+		}
+	}
+	if len(related) > 0 {
+		fmt.Fprintf(&sb, ".SH SEE ALSO\n")
+		for idx, page := range related {
+			separator := ","
+			if idx == len(related)-1 {
+				separator = ""
+			}
+			fmt.Fprintf(&sb, ".BR %s (%s)%s\n", roffEscape(page), roffEscape(g.Section), separator)
+		}
+	}
+
+	return sb.String()
+}
+
+// isVerbatimParagraph returns whether a paragraph should be emitted verbatim.
+func isVerbatimParagraph(paragraph string) bool {
+	return strings.HasPrefix(paragraph, indent4)
+}
+
+// roffParagraph writes a paragraph using the given macro (e.g., `.PP`). If the
+// paragraph starts with 4 spaces, it is emitted verbatim inside a `.nf` block.
+func roffParagraph(sb *strings.Builder, macro, paragraph string) {
+	fmt.Fprintf(sb, "%s\n", macro)
+	if !isVerbatimParagraph(paragraph) {
+		fmt.Fprintf(sb, "%s\n", roffEscape(paragraph))
+		return
+	}
+	fmt.Fprintf(sb, ".RS 4\n.nf\n")
+	for _, line := range strings.Split(paragraph, "\n") {
+		fmt.Fprintf(sb, "%s\n", roffEscape(strings.TrimPrefix(line, indent4)))
+	}
+	fmt.Fprintf(sb, ".fi\n.RE\n")
+}
+
+// roffEscape escapes text for inclusion into a roff document.
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	text = strings.ReplaceAll(text, "\n", " ")
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// roffQuote escapes and quotes a macro argument.
+func roffQuote(text string) string {
+	return `"` + strings.ReplaceAll(roffEscape(text), `"`, `\(dq`) + `"`
+}
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManPageGeneratorGenerate(t *testing.T) {
+	gen := NewManPageGenerator()
+	gen.Date = "2026-10-17"
+	gen.Source = "example 0.1.0"
+	gen.Manual = "User Commands"
+	pages := gen.Generate(newDocsTestDispatcher())
+
+	var names []string
+	contents := map[string]string{}
+	for _, page := range pages {
+		assert.Equal(t, "1", page.Section)
+		names = append(names, page.FileName())
+		contents[page.Name] = page.Content
+	}
+	assert.Equal(t, []string{
+		"example.1",
+		"example-fetch.1",
+		"example-net.1",
+		"example-net-curl.1",
+	}, names)
+
+	t.Run("root page", func(t *testing.T) {
+		content := contents["example"]
+		assert.Contains(t, content, ".TH EXAMPLE 1 \"2026\\-10\\-17\" \"example 0.1.0\" \"User Commands\"\n")
+		assert.Contains(t, content, ".SH NAME\nexample \\- Dispatcher for network commands.\n")
+		assert.Contains(t, content, ".SH SYNOPSIS\n.B example\n\\fIcommand\\fR [\\fIargs\\fR...]\n")
+		assert.Contains(t, content, ".SH COMMANDS\n")
+		assert.Contains(t, content, ".TP\n\\fBnet\\fR\nNetwork\\-related commands.\n")
+		assert.Contains(t, content, ".SS Deprecated\n.TP\n\\fBfetch\\fR\nOld name of curl.\n.IP\n"+
+			"This command is deprecated and will be removed in v2.0.0; use `net curl' instead.\n")
+		assert.Contains(t, content, ".SH SEE ALSO\n.BR example\\-fetch (1),\n.BR example\\-net (1)\n")
+		assert.NotContains(t, content, "debug")
+	})
+
+	t.Run("leaf page", func(t *testing.T) {
+		assert.Equal(t, `.TH EXAMPLE\-NET\-CURL 1 "2026\-10\-17" "example 0.1.0" "User Commands"
+.SH NAME
+example\-net\-curl \- Utility to transfer URLs.
+.SH SYNOPSIS
+.B example net curl
+[\fIargs\fR...]
+.SH DESCRIPTION
+.PP
+Utility to transfer URLs.
+.PP
+.RS 4
+.nf
+curl \-fsSL https://example.com/
+.fi
+.RE
+.PP
+Follows redirects.
+.SH ALIASES
+\fBexample net c\fR
+.SH SHORTCUTS
+.TP
+\fBexample net get\fR
+Shortcut for \fBexample net curl \-fsSL\fR.
+.SH SEE ALSO
+.BR example\-net (1)
+`, contents["example-net-curl"])
+	})
+
+	t.Run("nested dispatcher page", func(t *testing.T) {
+		content := contents["example-net"]
+		assert.Contains(t, content, ".SH NAME\nexample\\-net \\- Network\\-related commands.\n")
+		assert.Contains(t, content, ".SH DESCRIPTION\n.PP\nNetwork commands.\n.PP\n.RS 4\n.nf\n"+
+			"example net curl https://example.com/\n.fi\n.RE\n")
+		assert.Contains(t, content, ".TP\n\\fBc\\fR, \\fBcurl\\fR\nUtility to transfer URLs.\n.IP\n.RS 4\n.nf\n")
+		assert.Contains(t, content, ".SH SEE ALSO\n.BR example (1),\n.BR example\\-net\\-curl (1)\n")
+	})
+
+	t.Run("deprecated page", func(t *testing.T) {
+		content := contents["example-fetch"]
+		assert.Contains(t, content, ".SH DESCRIPTION\n.PP\nOld name of curl.\n.PP\n"+
+			"This command is deprecated and will be removed in v2.0.0; use `net curl' instead.\n")
+	})
+}
+
+func TestManPageGeneratorGenerateSkipsBuiltinCommands(t *testing.T) {
+	disp := newDocsTestDispatcher()
+	disp.AddVersionHandlers("0.1.0")
+	disp.AddCompletionHandlers()
+
+	var names []string
+	for _, page := range NewManPageGenerator().Generate(disp) {
+		names = append(names, page.FileName())
+		assert.NotContains(t, page.Content, "example\\-help")
+	}
+	assert.Equal(t, []string{"example.1", "example-fetch.1", "example-net.1", "example-net-curl.1"}, names)
+}
+
+func TestManPageGeneratorWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewManPageGenerator().WriteFiles(newDocsTestDispatcher(), dir))
+
+	data, err := os.ReadFile(filepath.Join(dir, "example-net-curl.1"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), ".TH EXAMPLE\\-NET\\-CURL 1")
+
+	_, err = os.Stat(filepath.Join(dir, "example-debug.1"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestManPageGeneratorWriteFilesError(t *testing.T) {
+	err := NewManPageGenerator().WriteFiles(newDocsTestDispatcher(), filepath.Join(t.TempDir(), "nonexistent"))
+	assert.Error(t, err)
+}
+
+func TestRoffEscape(t *testing.T) {
+	assert.Equal(t, `a \- b`, roffEscape("a - b"))
+	assert.Equal(t, `C:\eWindows`, roffEscape(`C:\Windows`))
+	assert.Equal(t, `\&.hidden`, roffEscape(".hidden"))
+	assert.Equal(t, `\&'quoted'`, roffEscape("'quoted'"))
+	assert.Equal(t, `"say \(dqhi\(dq"`, roffQuote(`say "hi"`))
+}
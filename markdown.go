@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bassosimone/runtimex"
+)
+
+// MarkdownPage is a Markdown document generated by [*MarkdownGenerator].
+type MarkdownPage struct {
+	// Content is the document content using the Markdown format.
+	Content string
+
+	// Name is the document name (e.g., `example-curl`).
+	Name string
+}
+
+// FileName returns the conventional document file name (e.g., `example-curl.md`).
+func (mp *MarkdownPage) FileName() string {
+	return mp.Name + ".md"
+}
+
+// MarkdownGenerator generates Markdown reference documentation
+// for a tree of [*DispatcherCommand].
+//
+// Construct using [NewMarkdownGenerator].
+type MarkdownGenerator struct{}
+
+// NewMarkdownGenerator creates a new [*MarkdownGenerator].
+func NewMarkdownGenerator() *MarkdownGenerator {
+	return &MarkdownGenerator{}
+}
+
+// Generate generates a Markdown document for the given [*DispatcherCommand]
+// (e.g., `example.md`) and for each non-hidden command reachable from it (e.g.,
+// `example-curl.md`), except for the built-in commands (e.g., `help`), which we
+// only list among the commands. Documents link to each other using their file names.
+//
+// Each document contains the description, the usage, the commands (only for
+// dispatchers), the aliases (only when there are aliases), the shortcuts (only
+// when there are shortcuts), and the related commands.
+//
+// Description paragraphs starting with 4 spaces are emitted as fenced code blocks.
+func (g *MarkdownGenerator) Generate(c *DispatcherCommand) []MarkdownPage {
+	var pages []MarkdownPage
+	runtimex.PanicOnError0(Walk(c, func(node *CommandNode) error {
+		if node.Hidden || node.isBuiltin() {
+			return SkipCommand
+		}
+		var sb strings.Builder
+		g.section(&sb, node, 1, func(path []string) string {
+			return strings.Join(path, "-") + ".md"
+		})
+		pages = append(pages, MarkdownPage{
+			Content: sb.String(),
+			Name:    strings.Join(node.Path, "-"),
+		})
+		return nil
+	}))
+	return pages
+}
+
+// GenerateSingle is like Generate but produces a single Markdown document
+// containing all the commands, which link to each other using anchors.
+//
+// The given [*DispatcherCommand] uses a level-1 heading and the
+// commands reachable from it use level-2 headings.
+func (g *MarkdownGenerator) GenerateSingle(c *DispatcherCommand) MarkdownPage {
+	var sb strings.Builder
+	runtimex.PanicOnError0(Walk(c, func(node *CommandNode) error {
+		if node.Hidden || node.isBuiltin() {
+			return SkipCommand
+		}
+		if sb.Len() > 0 {
+			fmt.Fprintf(&sb, "\n")
+		}
+		level := 1
+		if node.Parent != nil {
+			level = 2
+		}
+		g.section(&sb, node, level, func(path []string) string {
+			return "#" + markdownAnchor(strings.Join(path, " "))
+		})
+		return nil
+	}))
+	return MarkdownPage{Content: sb.String(), Name: strings.Join(c.fullPath(), "-")}
+}
+
+// WriteFiles generates the Markdown documents using Generate and
+// writes them into the given directory, which must already exist.
+func (g *MarkdownGenerator) WriteFiles(c *DispatcherCommand, dir string) error {
+	for _, page := range g.Generate(c) {
+		if err := os.WriteFile(filepath.Join(dir, page.FileName()), []byte(page.Content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// section writes the documentation of a node using the given heading
+// level and the given function to generate links to other commands.
+func (g *MarkdownGenerator) section(sb *strings.Builder, node *CommandNode, level int, link func(path []string) string) {
+	heading := strings.Repeat("#", level)
+	subheading := heading + "#"
+
+	// Title and description
+	fmt.Fprintf(sb, "%s %s\n", heading, markdownEscape(strings.Join(node.Path, " ")))
+	description := node.Description()
+	for _, paragraph := range description {
+		markdownParagraph(sb, paragraph)
+	}
+	if node.Command.Deprecated != nil {
+		markdownParagraph(sb, fmt.Sprintf("This command is deprecated%s.", node.Command.Deprecated.details()))
+	}
+
+	// Usage
+	fmt.Fprintf(sb, "\n%s Usage\n", subheading)
+	if node.Dispatcher != nil {
+		markdownParagraph(sb, fmt.Sprintf("%s%s <command> [args...]", indent4, strings.Join(node.Path, " ")))
+	} else {
+		markdownParagraph(sb, fmt.Sprintf("%s%s [args...]", indent4, strings.Join(node.Path, " ")))
+	}
+
+	// Commands
+	if node.Dispatcher != nil {
+		sections := node.Dispatcher.commandSections()
+		for _, section := range sections {
+			title := "Commands"
+			if len(sections) > 1 {
+				title = section.title
+			}
+			fmt.Fprintf(sb, "\n%s %s\n\n", subheading, markdownEscape(title))
+			for _, childName := range section.names {
+				child, aliases := node.Dispatcher.childCommand(childName)
+				entry := fmt.Sprintf("- [`%s`](%s)", childName, link(append(slices.Clone(node.Path), childName)))
+				if node.Dispatcher.isBuiltinCommand(childName) {
+					entry = fmt.Sprintf("- `%s`", childName) // we do not document the built-in commands
+				}
+				if len(aliases) > 0 {
+					entry += fmt.Sprintf(" (aliases: `%s`)", strings.Join(aliases, "`, `"))
+				}
+				if summary := firstParagraph(child.Descr); summary != "" && !isVerbatimParagraph(child.Descr[0]) {
+					entry += ": " + markdownEscape(summary)
+				}
+				if child.Deprecated != nil {
+					entry += " (deprecated)"
+				}
+				fmt.Fprintf(sb, "%s\n", entry)
+			}
+		}
+	}
+
+	// Aliases
+	if len(node.Aliases) > 0 {
+		fmt.Fprintf(sb, "\n%s Aliases\n\n", subheading)
+		parent := strings.Join(node.Path[:len(node.Path)-1], " ")
+		for _, alias := range node.Aliases {
+			fmt.Fprintf(sb, "- `%s %s`\n", parent, alias)
+		}
+	}
+
+	// Shortcuts
+	if len(node.Shortcuts) > 0 {
+		fmt.Fprintf(sb, "\n%s Shortcuts\n\n", subheading)
+		parent := strings.Join(node.Path[:len(node.Path)-1], " ")
+		for _, shortcut := range slices.Sorted(maps.Keys(node.Shortcuts)) {
+			expansion := joinShellWords(append(slices.Clone(node.Path), node.Shortcuts[shortcut]...))
+			fmt.Fprintf(sb, "- `%s %s`: shortcut for `%s`\n", parent, shortcut, expansion)
+		}
+	}
+
+	// See also
+	if node.Parent != nil {
+		fmt.Fprintf(sb, "\n%s See also\n\n", subheading)
+		fmt.Fprintf(sb, "- [%s](%s)\n", markdownEscape(strings.Join(node.Parent.Path, " ")), link(node.Parent.Path))
+	}
+}
+
+// markdownParagraph writes a paragraph preceded by an empty line. If the paragraph
+// starts with 4 spaces, it is emitted verbatim inside a fenced code block.
+func markdownParagraph(sb *strings.Builder, paragraph string) {
+	fmt.Fprintf(sb, "\n")
+	if !isVerbatimParagraph(paragraph) {
+		fmt.Fprintf(sb, "%s\n", markdownEscape(paragraph))
+		return
+	}
+	fence := "```"
+	for strings.Contains(paragraph, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(sb, "%s\n", fence)
+	for _, line := range strings.Split(paragraph, "\n") {
+		fmt.Fprintf(sb, "%s\n", strings.TrimPrefix(line, indent4))
+	}
+	fmt.Fprintf(sb, "%s\n", fence)
+}
+
+// markdownEscaper escapes the characters having a special meaning in inline Markdown.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	"\n", " ",
+)
+
+// markdownEscape escapes text for inclusion into a Markdown document.
+func markdownEscape(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// markdownAnchor returns the anchor that common Markdown renderers (e.g., GitHub)
+// generate for the given heading: lowercase, spaces replaced by dashes, and
+// punctuation other than dashes and underscores removed.
+func markdownAnchor(heading string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '-', r == '_', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return -1
+		}
+	}, heading)
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownGeneratorGenerate(t *testing.T) {
+	pages := NewMarkdownGenerator().Generate(newDocsTestDispatcher())
+
+	var names []string
+	contents := map[string]string{}
+	for _, page := range pages {
+		names = append(names, page.FileName())
+		contents[page.Name] = page.Content
+	}
+	assert.Equal(t, []string{
+		"example.md",
+		"example-fetch.md",
+		"example-net.md",
+		"example-net-curl.md",
+	}, names)
+
+	t.Run("root page", func(t *testing.T) {
+		content := contents["example"]
+		assert.Contains(t, content, "# example\n\nDispatcher for network commands.\n")
+		assert.Contains(t, content, "## Usage\n\n```\nexample <command> [args...]\n```\n")
+		assert.Contains(t, content, "## Commands\n\n"+
+			"- `help` (aliases: `-h`, `--help`): Show help about this command or about a subcommand.\n"+
+			"- [`net`](example-net.md): Network-related commands.\n")
+		assert.Contains(t, content, "## Deprecated\n\n- [`fetch`](example-fetch.md): Old name of curl. (deprecated)\n")
+		assert.NotContains(t, content, "debug")
+		assert.NotContains(t, content, "See also")
+	})
+
+	t.Run("leaf page", func(t *testing.T) {
+		assert.Equal(t, "# example net curl\n"+
+			"\n"+
+			"Utility to transfer URLs.\n"+
+			"\n"+
+			"```\n"+
+			"curl -fsSL https://example.com/\n"+
+			"```\n"+
+			"\n"+
+			"Follows redirects.\n"+
+			"\n"+
+			"## Usage\n"+
+			"\n"+
+			"```\n"+
+			"example net curl [args...]\n"+
+			"```\n"+
+			"\n"+
+			"## Aliases\n"+
+			"\n"+
+			"- `example net c`\n"+
+			"\n"+
+			"## Shortcuts\n"+
+			"\n"+
+			"- `example net get`: shortcut for `example net curl -fsSL`\n"+
+			"\n"+
+			"## See also\n"+
+			"\n"+
+			"- [example net](example-net.md)\n", contents["example-net-curl"])
+	})
+
+	t.Run("nested dispatcher page", func(t *testing.T) {
+		content := contents["example-net"]
+		assert.Contains(t, content, "# example net\n\nNetwork commands.\n\n```\nexample net curl https://example.com/\n```\n")
+		assert.Contains(t, content, "- [`curl`](example-net-curl.md) (aliases: `c`): Utility to transfer URLs.\n")
+		assert.Contains(t, content, "## See also\n\n- [example](example.md)\n")
+	})
+
+	t.Run("deprecated page", func(t *testing.T) {
+		assert.Contains(t, contents["example-fetch"], "\nThis command is deprecated and will be "+
+			"removed in v2.0.0; use \\`net curl' instead.\n")
+	})
+}
+
+func TestMarkdownGeneratorGenerateSingle(t *testing.T) {
+	page := NewMarkdownGenerator().GenerateSingle(newDocsTestDispatcher())
+	assert.Equal(t, "example.md", page.FileName())
+	assert.Contains(t, page.Content, "# example\n\nDispatcher for network commands.\n\n## Usage\n")
+	assert.Contains(t, page.Content, "- [`net`](#example-net): Network-related commands.\n")
+	assert.Contains(t, page.Content, "\n## example net\n\nNetwork commands.\n")
+	assert.Contains(t, page.Content, "- [`curl`](#example-net-curl) (aliases: `c`): Utility to transfer URLs.\n")
+	assert.Contains(t, page.Content, "\n## example net curl\n\nUtility to transfer URLs.\n")
+	assert.Contains(t, page.Content, "\n### Aliases\n\n- `example net c`\n\n### Shortcuts\n\n"+
+		"- `example net get`: shortcut for `example net curl -fsSL`\n\n### See also\n\n- [example net](#example-net)\n")
+	assert.NotContains(t, page.Content, "debug")
+	assert.NotContains(t, page.Content, "## example help")
+	assert.Contains(t, page.Content, "- `help` (aliases: `-h`, `--help`): Show help about this command or about a subcommand.\n")
+}
+
+func TestMarkdownGeneratorGenerateSingleNested(t *testing.T) {
+	net := newDocsTestDispatcher().Commands["net"].Cmd.(*DispatcherCommand)
+	page := NewMarkdownGenerator().GenerateSingle(net)
+	assert.Equal(t, "example-net.md", page.FileName())
+	assert.Contains(t, page.Content, "# example net\n")
+}
+
+func TestMarkdownGeneratorWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewMarkdownGenerator().WriteFiles(newDocsTestDispatcher(), dir))
+
+	data, err := os.ReadFile(filepath.Join(dir, "example-net-curl.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# example net curl\n")
+
+	_, err = os.Stat(filepath.Join(dir, "example-debug.md"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestMarkdownGeneratorWriteFilesError(t *testing.T) {
+	err := NewMarkdownGenerator().WriteFiles(newDocsTestDispatcher(), filepath.Join(t.TempDir(), "nonexistent"))
+	assert.Error(t, err)
+}
+
+func TestMarkdownParagraphFence(t *testing.T) {
+	var sb strings.Builder
+	markdownParagraph(&sb, "    echo ```")
+	assert.Equal(t, "\n````\necho ```\n````\n", sb.String())
+}
+
+func TestMarkdownEscape(t *testing.T) {
+	assert.Equal(t, `use \<name\> and \*not\* \_this\_ \[x\] \\`, markdownEscape(`use <name> and *not* _this_ [x] \`))
+}
+
+func TestMarkdownAnchor(t *testing.T) {
+	assert.Equal(t, "example-net-curl", markdownAnchor("example net curl"))
+	assert.Equal(t, "my_tool-v2", markdownAnchor("My_Tool v2!"))
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import "context"
+
+// Middleware wraps a [Command] to add cross-cutting behavior (e.g., logging,
+// timing, panic recovery, or authorization checks) and returns the wrapped [Command].
+type Middleware func(next Command) Command
+
+// Use adds middlewares wrapping the commands dispatched by the [*DispatcherCommand].
+//
+// The first middleware added is the outermost one. That is, after `c.Use(a, b)`,
+// dispatching the `curl` command runs `a(b(curl))`.
+//
+// We only wrap leaf commands and we do not wrap nested [*DispatcherCommand] instances.
+// Instead, nested instances inherit the middlewares, which wrap the middlewares they
+// add. For example, when `example` uses `a` and `example net` uses `b`, dispatching
+// `example net curl` runs `a(b(curl))`.
+//
+// We do not wrap the built-in commands (i.e., `help`, `version`, `completion`, and
+// its subcommands) and the hidden command implementing [*DispatcherCommand.Complete],
+// such that middlewares (e.g., authorization checks) cannot prevent users from
+// getting help and completions.
+func (c *DispatcherCommand) Use(mw ...Middleware) {
+	c.Middlewares = append(c.Middlewares, mw...)
+}
+
+// middlewaresKey is the context key for the inherited middlewares.
+type middlewaresKey struct{}
+
+// withMiddlewares returns a context containing the inherited middlewares
+// followed by the middlewares of the [*DispatcherCommand], along with them.
+func (c *DispatcherCommand) withMiddlewares(ctx context.Context) (context.Context, []Middleware) {
+	inherited, _ := ctx.Value(middlewaresKey{}).([]Middleware)
+	if len(c.Middlewares) <= 0 {
+		return ctx, inherited
+	}
+	chain := append(append([]Middleware{}, inherited...), c.Middlewares...)
+	return context.WithValue(ctx, middlewaresKey{}, chain), chain
+}
+
+// withoutMiddlewares returns a context where there are no inherited middlewares.
+func withoutMiddlewares(ctx context.Context) context.Context {
+	return context.WithValue(ctx, middlewaresKey{}, []Middleware(nil))
+}
+
+// wrapCommand wraps the given [Command] with the given middlewares, such
+// that the first middleware is the outermost one.
+func wrapCommand(cmd Command, chain []Middleware) Command {
+	for idx := len(chain) - 1; idx >= 0; idx-- {
+		cmd = chain[idx](cmd)
+	}
+	return cmd
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTracingMiddleware returns a [Middleware] appending its name to the
+// trace before and after running the wrapped command.
+func newTracingMiddleware(name string, trace *[]string) Middleware {
+	return func(next Command) Command {
+		return CommandFunc(func(ctx context.Context, args []string) error {
+			*trace = append(*trace, name+":before")
+			err := next.Main(ctx, args)
+			*trace = append(*trace, name+":after")
+			return err
+		})
+	}
+}
+
+func TestDispatcherCommandUse(t *testing.T) {
+	var trace []string
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.Stdout = &bytes.Buffer{}
+	net.AddCommand("curl", CommandFunc(func(ctx context.Context, args []string) error {
+		trace = append(trace, "curl")
+		return nil
+	}), "Utility to transfer URLs.")
+	net.Use(newTracingMiddleware("c", &trace))
+
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.Stdout = &bytes.Buffer{}
+	disp.AddCommand("net", net, "Network commands.")
+	disp.AddCommand("version", CommandFunc(func(ctx context.Context, args []string) error {
+		trace = append(trace, "version")
+		return nil
+	}), "Show the version number and exit.")
+	disp.AddCompletionHandlers()
+	disp.Use(newTracingMiddleware("a", &trace), newTracingMiddleware("b", &trace))
+
+	testCases := []struct {
+		name   string
+		args   []string
+		expect []string
+	}{{
+		name:   "leaf command",
+		args:   []string{"version"},
+		expect: []string{"a:before", "b:before", "version", "b:after", "a:after"},
+	}, {
+		name: "nested dispatcher",
+		args: []string{"net", "curl"},
+		expect: []string{
+			"a:before", "b:before", "c:before", "curl", "c:after", "b:after", "a:after",
+		},
+	}, {
+		name:   "built-in help command",
+		args:   []string{"help"},
+		expect: nil,
+	}, {
+		name:   "built-in help flag",
+		args:   []string{"net", "--help"},
+		expect: nil,
+	}, {
+		name:   "built-in completion command",
+		args:   []string{"completion", "bash"},
+		expect: nil,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			trace = nil
+			err := disp.Main(context.Background(), tc.args)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, trace)
+		})
+	}
+
+	t.Run("does not leak to siblings", func(t *testing.T) {
+		// running `net curl` must not add the middlewares of `net` to `version`
+		require.NoError(t, disp.Main(context.Background(), []string{"net", "curl"}))
+		trace = nil
+		require.NoError(t, disp.Main(context.Background(), []string{"version"}))
+		assert.Equal(t, []string{"a:before", "b:before", "version", "b:after", "a:after"}, trace)
+	})
+
+	t.Run("can short circuit", func(t *testing.T) {
+		expectErr := errors.New("permission denied")
+		disp.Use(func(next Command) Command {
+			return CommandFunc(func(ctx context.Context, args []string) error {
+				return expectErr
+			})
+		})
+
+		trace = nil
+		err := disp.Main(context.Background(), []string{"net", "curl"})
+		assert.ErrorIs(t, err, expectErr)
+		assert.Equal(t, []string{"a:before", "b:before", "b:after", "a:after"}, trace)
+	})
+}
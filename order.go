@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+// CommandOrder is the policy for sorting the commands of a [*DispatcherCommand].
+type CommandOrder int
+
+const (
+	// CommandOrderAlphabetical sorts the commands alphabetically by name.
+	CommandOrderAlphabetical = CommandOrder(iota)
+
+	// CommandOrderRegistration sorts the commands in the order in which they were
+	// added using [*DispatcherCommand.AddCommand]. The commands directly added to
+	// the Commands map come next, sorted alphabetically. The built-in commands
+	// (i.e., `help`, `version`, and `completion`) come last.
+	CommandOrderRegistration
+
+	// CommandOrderWeight sorts the commands by increasing weight, which is set using
+	// [*DispatcherCommand.MustSetCommandWeight], and then alphabetically. The built-in
+	// commands come last, sorted in the same way.
+	CommandOrderWeight
+)
+
+// SortedCommandNames returns the command names sorted according to CommandOrder.
+//
+// Custom [UsagePrinter] implementations should use this method to honor CommandOrder.
+func (c *DispatcherCommand) SortedCommandNames() []string {
+	names := slices.Sorted(maps.Keys(c.Commands))
+	switch c.CommandOrder {
+	case CommandOrderRegistration:
+		position := func(name string) int {
+			if idx := slices.Index(c.registrationOrder, name); idx >= 0 {
+				return idx
+			}
+			return len(c.registrationOrder)
+		}
+		slices.SortStableFunc(names, func(a, b string) int {
+			return cmp.Or(c.compareBuiltin(a, b), cmp.Compare(position(a), position(b)))
+		})
+
+	case CommandOrderWeight:
+		slices.SortStableFunc(names, func(a, b string) int {
+			return cmp.Or(c.compareBuiltin(a, b), cmp.Compare(c.Commands[a].Weight, c.Commands[b].Weight))
+		})
+	}
+	return names
+}
+
+// compareBuiltin compares two command names such that built-in commands come last.
+func (c *DispatcherCommand) compareBuiltin(a, b string) int {
+	switch builtinA, builtinB := c.isBuiltinCommand(a), c.isBuiltinCommand(b); {
+	case builtinA == builtinB:
+		return 0
+	case builtinA:
+		return 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherCommandSortedCommandNames(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("dig", &testCommand{})
+	disp.AddCommand("curl", &testCommand{})
+	disp.AddCommand("ping", &testCommand{})
+	disp.Commands["added-directly"] = NewDescribedCommand(&testCommand{})
+
+	t.Run("alphabetical", func(t *testing.T) {
+		assert.Equal(t, CommandOrderAlphabetical, disp.CommandOrder)
+		assert.Equal(t, []string{"added-directly", "curl", "dig", "help", "ping"}, disp.SortedCommandNames())
+	})
+
+	t.Run("registration", func(t *testing.T) {
+		disp.CommandOrder = CommandOrderRegistration
+		disp.AddCommand("dig", &testCommand{}) // re-adding keeps the original position
+		assert.Equal(t, []string{"dig", "curl", "ping", "added-directly", "help"}, disp.SortedCommandNames())
+	})
+
+	t.Run("weight", func(t *testing.T) {
+		disp.CommandOrder = CommandOrderWeight
+		disp.AddVersionHandlers("0.1.0")
+		disp.MustSetCommandWeight("help", 100)
+		disp.MustSetCommandWeight("version", -100)
+		disp.MustSetCommandWeight("ping", -1)
+		disp.MustSetCommandWeight("dig", 10)
+		assert.Equal(t, []string{"ping", "added-directly", "curl", "dig", "version", "help"}, disp.SortedCommandNames())
+	})
+
+	t.Run("replacing a built-in command", func(t *testing.T) {
+		disp.CommandOrder = CommandOrderRegistration
+		disp.AddCommand("help", &testCommand{})
+		assert.Equal(t, []string{"help", "dig", "curl", "ping", "added-directly", "version"}, disp.SortedCommandNames())
+	})
+}
+
+func TestDispatcherCommandMustSetCommandWeightPanicsForUnknownCommand(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	assert.Panics(t, func() {
+		disp.MustSetCommandWeight("nope", 1)
+	})
+}
+
+func TestDefaultUsagePrinterHonorsCommandOrder(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.CommandOrder = CommandOrderRegistration
+	disp.AddCommand("dig", &testCommand{})
+	disp.AddCommand("curl", &testCommand{})
+	var stdout bytes.Buffer
+	disp.Stdout = &stdout
+
+	require.NoError(t, disp.Main(context.Background(), []string{"--help"}))
+	output := stdout.String()
+	help := strings.Index(output, "-h, --help, help\n")
+	dig := strings.Index(output, "    dig\n")
+	curl := strings.Index(output, "    curl\n")
+	require.True(t, help >= 0 && dig >= 0 && curl >= 0)
+	assert.Less(t, dig, curl)
+	assert.Less(t, curl, help)
+}
+
+func TestDispatcherCommandCompleteHonorsCommandOrder(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.CommandOrder = CommandOrderWeight
+	disp.AddCommand("curl", &testCommand{})
+	disp.AddCommand("dig", &testCommand{})
+	disp.MustSetCommandWeight("dig", -1)
+
+	completions, err := disp.Complete(context.Background(), []string{""})
+	require.NoError(t, err)
+	require.NotEmpty(t, completions)
+	assert.Equal(t, "dig", completions[0].Value)
+}
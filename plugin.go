@@ -0,0 +1,470 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnablePlugins enables dispatching to external plugins, that is, executables named
+// after the command path followed by the command name (e.g., `example-foo` for the
+// `foo` command of `example`, or `example-net-foo` for the `foo` command of
+// `example net`). We search plugins in the given directories, in order, followed
+// by the absolute directories listed in the PATH environment variable.
+//
+// We only look for a plugin when the command name does not match the name or the
+// alias of a registered command, such that plugins cannot shadow registered commands,
+// and before trying prefix matching. Plugins run with the remaining arguments, share
+// the stdin, the stdout, and the stderr, and are killed when the context is done.
+//
+// The [*DefaultUsagePrinter] lists the discovered plugins in the "Plugins" section,
+// using the description provided by the plugins themselves (see [PluginDescribeFlag]).
+// We read the plugin directories once for the lifetime of the [*DispatcherCommand].
+func (c *DispatcherCommand) EnablePlugins(dirs ...string) {
+	c.PluginDirs = append(c.PluginDirs, dirs...)
+	c.pluginsEnabled = true
+}
+
+// PluginDescribeFlag is the flag with which we invoke plugins to obtain their
+// [PluginDescription]. Plugins supporting this flag print the description as a JSON
+// object on the stdout and exit successfully. For example:
+//
+//	{
+//	  "aliases": ["f"],
+//	  "description": ["Frobnicate the network."],
+//	  "hidden": false
+//	}
+//
+// We describe the plugins in parallel, we give them PluginDescribeTimeout to describe
+// themselves, and we use an empty description when they fail or print invalid JSON.
+//
+// We cache the descriptions inside `$XDG_CACHE_HOME/<program>/plugins.json` (or inside
+// `$HOME/.cache/<program>/plugins.json` when XDG_CACHE_HOME is not set), where
+// `<program>` is the first element of the Path, and we describe a plugin again only
+// when the modification time or the size of its executable change.
+//
+// We only describe plugins when listing them (e.g., to print the help or to complete
+// a command name). When a command name matches neither a registered command nor a
+// plugin name, we only use the cached descriptions to search a plugin alias.
+const PluginDescribeFlag = "--vclip-describe"
+
+// PluginDescription is the description that a plugin prints when invoked
+// using [PluginDescribeFlag].
+type PluginDescription struct {
+	// Aliases contains the plugin aliases. We ignore the aliases conflicting with
+	// registered commands and aliases, or with the names of other plugins.
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Description contains the description paragraphs.
+	Description []string `json:"description,omitempty"`
+
+	// Hidden indicates that the plugin should not appear in the help, in
+	// the completions, and in the generated documentation.
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// plugin is a plugin found by [*DispatcherCommand.discoverPlugins].
+type plugin struct {
+	// aliases contains the aliases that do not conflict with other commands.
+	aliases []string
+
+	// command is the command running the plugin along with its description.
+	command DescribedCommand
+
+	// name is the plugin name (e.g., `foo` for `example-foo`).
+	name string
+}
+
+// pluginDirs returns the PluginDirs followed by the absolute directories in the PATH.
+func (c *DispatcherCommand) pluginDirs() []string {
+	dirs := slices.Clone(c.PluginDirs)
+	path, _ := c.LookupEnv("PATH")
+	for _, dir := range filepath.SplitList(path) {
+		// like [exec.LookPath], refuse to run executables relative to the current directory
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// pluginPrefix returns the prefix of the plugins file names (e.g., `example-net-`).
+func (c *DispatcherCommand) pluginPrefix() string {
+	return strings.Join(c.fullPath(), "-") + "-"
+}
+
+// isValidPluginName returns whether we may search a plugin with the given name.
+func isValidPluginName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, "-") && !strings.ContainsAny(name, `/\`)
+}
+
+// findPluginPath returns the path of the plugin with the given name, if any.
+func (c *DispatcherCommand) findPluginPath(name string) (string, bool) {
+	for _, dir := range c.pluginDirs() {
+		for _, fileName := range pluginFileNames(c.pluginPrefix() + name) {
+			path := filepath.Join(dir, fileName)
+			if info, err := os.Stat(path); err == nil && isPluginExecutable(info) {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// findPlugin returns the real name of the plugin with the given name or alias
+// along with the command running it, if any.
+func (c *DispatcherCommand) findPlugin(name string) (string, DescribedCommand, bool) {
+	if !c.pluginsEnabled || !isValidPluginName(name) {
+		return "", DescribedCommand{}, false
+	}
+	if path, found := c.findPluginPath(name); found {
+		// no need to describe the plugin just to run it
+		return name, c.newPluginCommand(path, PluginDescription{}), true
+	}
+	for _, plugin := range c.listPlugins(c.cachedPluginDescriptions) {
+		if slices.Contains(plugin.aliases, name) {
+			return plugin.name, plugin.command, true
+		}
+	}
+	return "", DescribedCommand{}, false
+}
+
+// discoverPlugins returns the plugins we can dispatch to sorted by name, that
+// is, excluding the ones shadowed by registered commands or by other plugins.
+func (c *DispatcherCommand) discoverPlugins() []*plugin {
+	return c.listPlugins(c.describePlugins)
+}
+
+// listPlugins is like discoverPlugins but uses the given function to
+// obtain the descriptions of the plugins given their paths.
+func (c *DispatcherCommand) listPlugins(describe func(paths []string) map[string]PluginDescription) []*plugin {
+	if !c.pluginsEnabled {
+		return nil
+	}
+	paths := maps.Clone(c.findPluginPaths())
+	maps.DeleteFunc(paths, func(name, path string) bool {
+		_, found := c.findCommand(name)
+		return found
+	})
+
+	// describe the plugins and assign the non-conflicting aliases
+	descrs := describe(slices.Sorted(maps.Values(paths)))
+	var plugins []*plugin
+	taken := func(name string) bool {
+		_, registered := c.findCommand(name)
+		_, discovered := paths[name]
+		return registered || discovered || slices.ContainsFunc(plugins, func(p *plugin) bool {
+			return slices.Contains(p.aliases, name)
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(paths)) {
+		descr := descrs[paths[name]]
+		var aliases []string
+		for _, alias := range descr.Aliases {
+			if isValidPluginName(alias) && !taken(alias) && !slices.Contains(aliases, alias) {
+				aliases = append(aliases, alias)
+			}
+		}
+		plugins = append(plugins, &plugin{
+			aliases: aliases,
+			command: c.newPluginCommand(paths[name], descr),
+			name:    name,
+		})
+	}
+	return plugins
+}
+
+// findPluginPaths returns the paths of the plugin executables by name, where the first
+// directory wins. We cache the result for the lifetime of the [*DispatcherCommand].
+func (c *DispatcherCommand) findPluginPaths() map[string]string {
+	if c.pluginPaths != nil {
+		return c.pluginPaths
+	}
+	paths := map[string]string{}
+	for _, dir := range c.pluginDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // like the shell, ignore nonexistent or unreadable directories
+		}
+		for _, entry := range entries {
+			name, found := strings.CutPrefix(entry.Name(), c.pluginPrefix())
+			if !found {
+				continue
+			}
+			name, found = pluginBaseName(name)
+			if _, exists := paths[name]; !found || exists || !isValidPluginName(name) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(path); err == nil && isPluginExecutable(info) {
+				paths[name] = path
+			}
+		}
+	}
+	c.pluginPaths = paths
+	return paths
+}
+
+// childNames returns the SortedCommandNames followed by the names of the plugins.
+func (c *DispatcherCommand) childNames() []string {
+	names := c.SortedCommandNames()
+	for _, plugin := range c.discoverPlugins() {
+		names = append(names, plugin.name)
+	}
+	return names
+}
+
+// childCommand returns the registered command or the plugin with the given
+// name along with its aliases. The name MUST NOT be an alias.
+func (c *DispatcherCommand) childCommand(name string) (DescribedCommand, []string) {
+	if command, found := c.Commands[name]; found {
+		return command, c.CommandNameToAliases[name]
+	}
+	for _, plugin := range c.discoverPlugins() {
+		if plugin.name == name {
+			return plugin.command, plugin.aliases
+		}
+	}
+	return DescribedCommand{}, nil
+}
+
+// newPluginCommand returns the command running the given plugin along with its description.
+func (c *DispatcherCommand) newPluginCommand(path string, descr PluginDescription) DescribedCommand {
+	command := NewDescribedCommand(&pluginCommand{
+		path:   path,
+		stderr: c.Stderr,
+		stdin:  c.Stdin,
+		stdout: c.Stdout,
+	}, descr.Description...)
+	command.Hidden = descr.Hidden
+	return command
+}
+
+// pluginCacheEntry is an entry of the persistent cache of the plugin descriptions.
+type pluginCacheEntry struct {
+	// Description is the plugin description.
+	Description PluginDescription `json:"description"`
+
+	// ModTime is the modification time of the plugin executable.
+	ModTime time.Time `json:"mod_time"`
+
+	// Size is the size of the plugin executable.
+	Size int64 `json:"size"`
+}
+
+// matches returns whether the entry describes the given plugin executable.
+func (e pluginCacheEntry) matches(info os.FileInfo) bool {
+	return e.ModTime.Equal(info.ModTime()) && e.Size == info.Size()
+}
+
+// pluginCacheFileName returns the name of the persistent cache of the plugin descriptions.
+func (c *DispatcherCommand) pluginCacheFileName() (string, bool) {
+	dir, found := c.LookupEnv("XDG_CACHE_HOME")
+	if !found || dir == "" {
+		home, found := c.LookupEnv("HOME")
+		if !found || home == "" {
+			return "", false
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, c.programName(), "plugins.json"), true
+}
+
+// readPluginCache reads the persistent cache of the plugin descriptions.
+func (c *DispatcherCommand) readPluginCache() map[string]pluginCacheEntry {
+	entries := map[string]pluginCacheEntry{}
+	if name, found := c.pluginCacheFileName(); found {
+		if data, err := os.ReadFile(name); err == nil {
+			_ = json.Unmarshal(data, &entries) // like a missing cache, ignore a broken cache
+		}
+	}
+	return entries
+}
+
+// writePluginCache merges the given entries into the persistent cache of the plugin
+// descriptions, dropping the entries of the plugins that no longer exist.
+func (c *DispatcherCommand) writePluginCache(entries map[string]pluginCacheEntry) {
+	name, found := c.pluginCacheFileName()
+	if !found {
+		return
+	}
+	merged := c.readPluginCache()
+	maps.Copy(merged, entries)
+	maps.DeleteFunc(merged, func(path string, entry pluginCacheEntry) bool {
+		_, err := os.Stat(path)
+		return err != nil
+	})
+	data, err := json.Marshal(merged)
+	if err != nil || os.MkdirAll(filepath.Dir(name), 0755) != nil {
+		return
+	}
+
+	// replace the cache atomically such that concurrent readers never see a partial file
+	temp, err := os.CreateTemp(filepath.Dir(name), "plugins-*.json")
+	if err != nil {
+		return
+	}
+	_, err = temp.Write(data)
+	if err = errors.Join(err, temp.Close()); err != nil || os.Rename(temp.Name(), name) != nil {
+		os.Remove(temp.Name())
+	}
+}
+
+// cachedPluginDescriptions returns the descriptions of the given plugins that we
+// have already cached, without invoking the plugins using [PluginDescribeFlag].
+func (c *DispatcherCommand) cachedPluginDescriptions(paths []string) map[string]PluginDescription {
+	if c.pluginCache == nil {
+		c.pluginCache = c.readPluginCache()
+	}
+	if c.pluginDescriptions == nil {
+		c.pluginDescriptions = map[string]PluginDescription{}
+	}
+	descrs := map[string]PluginDescription{}
+	for _, path := range paths {
+		if descr, found := c.pluginDescriptions[path]; found {
+			descrs[path] = descr
+			continue
+		}
+		entry, found := c.pluginCache[path]
+		if info, err := os.Stat(path); found && err == nil && entry.matches(info) {
+			c.pluginDescriptions[path] = entry.Description
+			descrs[path] = entry.Description
+		}
+	}
+	return descrs
+}
+
+// maxParallelPluginDescribes is the maximum number of plugins that
+// describePlugins invokes at the same time.
+const maxParallelPluginDescribes = 8
+
+// describePlugins returns the descriptions of the given plugins, invoking in parallel
+// the plugins whose description is not cached using [PluginDescribeFlag].
+func (c *DispatcherCommand) describePlugins(paths []string) map[string]PluginDescription {
+	descrs := c.cachedPluginDescriptions(paths)
+	var uncached []string
+	for _, path := range paths {
+		if _, found := descrs[path]; !found {
+			uncached = append(uncached, path)
+		}
+	}
+
+	var (
+		described = map[string]PluginDescription{}
+		entries   = map[string]pluginCacheEntry{}
+		mu        sync.Mutex
+		sema      = make(chan struct{}, maxParallelPluginDescribes)
+		wg        sync.WaitGroup
+	)
+	for _, path := range uncached {
+		wg.Go(func() {
+			sema <- struct{}{}
+			defer func() { <-sema }()
+			info, statErr := os.Stat(path)
+			descr := c.describePlugin(path)
+			mu.Lock()
+			defer mu.Unlock()
+			described[path] = descr
+			if statErr == nil {
+				entries[path] = pluginCacheEntry{Description: descr, ModTime: info.ModTime(), Size: info.Size()}
+			}
+		})
+	}
+	wg.Wait()
+	for path, descr := range described {
+		descrs[path] = descr
+		c.pluginDescriptions[path] = descr
+	}
+	if len(entries) > 0 {
+		maps.Copy(c.pluginCache, entries)
+		c.writePluginCache(entries)
+	}
+	return descrs
+}
+
+// describePlugin invokes the given plugin using [PluginDescribeFlag] and returns its description.
+func (c *DispatcherCommand) describePlugin(path string) PluginDescription {
+	ctx, cancel := context.WithTimeout(context.Background(), c.PluginDescribeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, PluginDescribeFlag)
+	cmd.WaitDelay = c.PluginDescribeTimeout // do not wait forever for orphaned children
+	var descr PluginDescription
+	if data, err := cmd.Output(); err != nil || json.Unmarshal(data, &descr) != nil {
+		descr = PluginDescription{} // do not use a partially decoded description
+	}
+	return descr
+}
+
+// pluginCommand is the [Command] running a plugin.
+type pluginCommand struct {
+	path   string
+	stderr io.Writer
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+var _ Command = &pluginCommand{}
+
+// Main implements [Command].
+func (pc *pluginCommand) Main(ctx context.Context, args []string) error {
+	cmd := exec.CommandContext(ctx, pc.path, args...)
+	cmd.Stderr = pc.stderr
+	cmd.Stdin = pc.stdin
+	cmd.Stdout = pc.stdout
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	return &PluginError{Err: err, Path: pc.path}
+}
+
+// PluginError is the error returned when a plugin fails.
+//
+// Use [errors.As] to access the fields.
+type PluginError struct {
+	// Err is the underlying error (e.g., [*exec.ExitError]).
+	Err error
+
+	// Path is the path of the plugin executable.
+	Path string
+}
+
+var _ QuietExitCoder = &PluginError{}
+
+// Error implements error.
+func (err *PluginError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Path, err.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (err *PluginError) Unwrap() error {
+	return err.Err
+}
+
+// ExitCode implements [ExitCoder] and returns the exit code of the plugin or -1
+// when the plugin did not exit normally (e.g., it failed to start or it was killed).
+func (err *PluginError) ExitCode() int {
+	var exitErr *exec.ExitError
+	if errors.As(err.Err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// QuietExit implements [QuietExitCoder] and returns true when the plugin exited with
+// a nonzero exit code, since the plugin is responsible for printing its own errors.
+func (err *PluginError) QuietExit() bool {
+	return err.ExitCode() > 0
+}
@@ -0,0 +1,358 @@
+//go:build unix
+
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestPlugin writes a shell script with the given name and body.
+func writeTestPlugin(t *testing.T, dir, name, body string, perm os.FileMode) {
+	data := "#!/bin/sh\n" + body + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), perm))
+}
+
+// pluginTest is the fixture created by newPluginTest.
+type pluginTest struct {
+	disp      *DispatcherCommand
+	pluginDir string
+	pathDir   string
+	stdout    *bytes.Buffer
+	stderr    *bytes.Buffer
+}
+
+func newPluginTest(t *testing.T) *pluginTest {
+	pluginDir, pathDir := t.TempDir(), t.TempDir()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AllowPrefixMatching = true
+	disp.LookupEnv = func(key string) (string, bool) {
+		if key == "PATH" {
+			return strings.Join([]string{"relative", pathDir}, string(filepath.ListSeparator)), true
+		}
+		return "", false
+	}
+	disp.Stderr = stderr
+	disp.Stdin = strings.NewReader("input\n")
+	disp.Stdout = stdout
+	disp.AddCommand("version", CommandFunc(func(ctx context.Context, args []string) error {
+		return nil
+	}), "Show the version number and exit.")
+	disp.EnablePlugins(pluginDir)
+	return &pluginTest{disp: disp, pluginDir: pluginDir, pathDir: pathDir, stdout: stdout, stderr: stderr}
+}
+
+func TestDispatcherCommandPlugins(t *testing.T) {
+	t.Run("we run the plugin with the remaining args and forward stdio", func(t *testing.T) {
+		pt := newPluginTest(t)
+		writeTestPlugin(t, pt.pluginDir, "example-foo", `echo "$@"; cat; echo oops 1>&2`, 0o755)
+
+		err := pt.disp.Main(context.Background(), []string{"foo", "-x", "bar"})
+		require.NoError(t, err)
+		assert.Equal(t, "-x bar\ninput\n", pt.stdout.String())
+		assert.Equal(t, "oops\n", pt.stderr.String())
+	})
+
+	t.Run("we search the plugin directories before the PATH", func(t *testing.T) {
+		pt := newPluginTest(t)
+		writeTestPlugin(t, pt.pluginDir, "example-foo", "echo plugin-dir", 0o755)
+		writeTestPlugin(t, pt.pathDir, "example-foo", "echo path", 0o755)
+		writeTestPlugin(t, pt.pathDir, "example-bar", "echo path", 0o755)
+
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"foo"}))
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"bar"}))
+		assert.Equal(t, "plugin-dir\npath\n", pt.stdout.String())
+	})
+
+	t.Run("plugins cannot shadow registered commands", func(t *testing.T) {
+		pt := newPluginTest(t)
+		writeTestPlugin(t, pt.pluginDir, "example-version", "echo plugin", 0o755)
+
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"version"}))
+		assert.Empty(t, pt.stdout.String())
+	})
+
+	t.Run("we prefer plugins to prefix matching", func(t *testing.T) {
+		pt := newPluginTest(t)
+		writeTestPlugin(t, pt.pluginDir, "example-v", "echo plugin", 0o755)
+
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"v"}))
+		assert.Equal(t, "plugin\n", pt.stdout.String())
+	})
+
+	t.Run("we ignore non-executable files", func(t *testing.T) {
+		pt := newPluginTest(t)
+		writeTestPlugin(t, pt.pluginDir, "example-foo", "echo plugin", 0o644)
+
+		err := pt.disp.Main(context.Background(), []string{"foo"})
+		assert.ErrorIs(t, err, ErrCommandNotFound)
+	})
+
+	t.Run("we do not search plugins unless enabled", func(t *testing.T) {
+		pt := newPluginTest(t)
+		pt.disp.pluginsEnabled = false
+		writeTestPlugin(t, pt.pluginDir, "example-foo", "echo plugin", 0o755)
+
+		err := pt.disp.Main(context.Background(), []string{"foo"})
+		assert.ErrorIs(t, err, ErrCommandNotFound)
+	})
+
+	t.Run("nested dispatchers use the full command path", func(t *testing.T) {
+		pt := newPluginTest(t)
+		net := NewDispatcherCommand("net", vflag.ContinueOnError)
+		net.LookupEnv = pt.disp.LookupEnv
+		net.Stdout = pt.stdout
+		net.EnablePlugins(pt.pluginDir)
+		pt.disp.AddCommand("net", net, "Network commands.")
+		writeTestPlugin(t, pt.pluginDir, "example-net-foo", "echo nested", 0o755)
+
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"net", "foo"}))
+		assert.Equal(t, "nested\n", pt.stdout.String())
+	})
+}
+
+func TestDispatcherCommandPluginsExitCode(t *testing.T) {
+	t.Run("with ContinueOnError", func(t *testing.T) {
+		pt := newPluginTest(t)
+		writeTestPlugin(t, pt.pluginDir, "example-foo", "exit 3", 0o755)
+
+		err := pt.disp.Main(context.Background(), []string{"foo"})
+		var pluginErr *PluginError
+		require.ErrorAs(t, err, &pluginErr)
+		assert.Equal(t, filepath.Join(pt.pluginDir, "example-foo"), pluginErr.Path)
+		assert.Equal(t, 3, pluginErr.ExitCode())
+	})
+
+	t.Run("with ExitOnError", func(t *testing.T) {
+		pt := newPluginTest(t)
+		pt.disp.ErrorHandling = vflag.ExitOnError
+		var exitCode int
+		pt.disp.Exit = func(status int) {
+			exitCode = status
+			panic("exit")
+		}
+		writeTestPlugin(t, pt.pluginDir, "example-foo", "exit 3", 0o755)
+
+		assert.PanicsWithValue(t, "exit", func() {
+			pt.disp.Main(context.Background(), []string{"foo"})
+		})
+		assert.Equal(t, 3, exitCode)
+		assert.Empty(t, pt.stderr.String())
+	})
+}
+
+func TestDispatcherCommandPluginsCancellation(t *testing.T) {
+	pt := newPluginTest(t)
+	writeTestPlugin(t, pt.pluginDir, "example-foo", "exec sleep 10", 0o755)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := pt.disp.Main(ctx, []string{"foo"})
+	var pluginErr *PluginError
+	require.ErrorAs(t, err, &pluginErr)
+	assert.Equal(t, -1, pluginErr.ExitCode())
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestDispatcherCommandPluginsUsage(t *testing.T) {
+	pt := newPluginTest(t)
+	writeTestPlugin(t, pt.pluginDir, "example-foo", "true", 0o755)
+	writeTestPlugin(t, pt.pathDir, "example-foo", "true", 0o755)
+	writeTestPlugin(t, pt.pathDir, "example-bar", "true", 0o755)
+	writeTestPlugin(t, pt.pathDir, "example-version", "true", 0o755)
+	writeTestPlugin(t, pt.pathDir, "example-baz", "true", 0o644)
+	writeTestPlugin(t, pt.pathDir, "other-qux", "true", 0o755)
+
+	assert.Equal(t, []string{"help", "version", "bar", "foo"}, pt.disp.childNames())
+
+	require.NoError(t, pt.disp.Main(context.Background(), []string{"--help"}))
+	assert.Contains(t, pt.stdout.String(), "Plugins\n\n    bar\n\n    foo\n")
+	assert.Contains(t, pt.stdout.String(), "Plugins are executables named `example-<command>'")
+}
+
+// describingTestPluginBody returns the body of a plugin that describes itself
+// using the given JSON and logs each handshake into the given file.
+func describingTestPluginBody(descr, log string) string {
+	return `if [ "$1" = "` + PluginDescribeFlag + `" ]; then
+  echo describe >> '` + log + `'
+  echo '` + descr + `'
+  exit 0
+fi
+echo "run $@"`
+}
+
+func TestDispatcherCommandPluginsDescribe(t *testing.T) {
+	t.Run("we use the description, the aliases, and the hidden status", func(t *testing.T) {
+		pt := newPluginTest(t)
+		log := filepath.Join(t.TempDir(), "log")
+		writeTestPlugin(t, pt.pluginDir, "example-foo", describingTestPluginBody(
+			`{"aliases":["f","version","bar","f"],"description":["Frobnicate things."]}`, log), 0o755)
+		writeTestPlugin(t, pt.pluginDir, "example-bar", describingTestPluginBody(
+			`{"aliases":["b"],"hidden":true}`, log), 0o755)
+
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"--help"}))
+		assert.Contains(t, pt.stdout.String(), "Plugins\n\n    f, foo\n\n        Frobnicate things.\n\nHints\n")
+
+		pt.stdout.Reset()
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"f", "x"}))
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"b", "y"}))
+		assert.Equal(t, "run x\nrun y\n", pt.stdout.String())
+
+		completions, err := pt.disp.Complete(context.Background(), []string{"f"})
+		require.NoError(t, err)
+		assert.Equal(t, []Completion{
+			{Value: "foo", Descr: "Frobnicate things."},
+			{Value: "f", Descr: "Frobnicate things."},
+		}, completions)
+
+		tree := pt.disp.ExportCommandTree()
+		require.NotNil(t, tree.findCommand("foo"))
+		assert.Equal(t, []string{"f"}, tree.findCommand("foo").Aliases)
+		assert.Equal(t, []string{"Frobnicate things."}, tree.findCommand("foo").Description)
+		assert.True(t, tree.findCommand("bar").Hidden)
+
+		// we describe each plugin once
+		data, err := os.ReadFile(log)
+		require.NoError(t, err)
+		assert.Equal(t, "describe\ndescribe\n", string(data))
+	})
+
+	t.Run("we do not describe a plugin just to run it", func(t *testing.T) {
+		pt := newPluginTest(t)
+		log := filepath.Join(t.TempDir(), "log")
+		writeTestPlugin(t, pt.pluginDir, "example-foo", describingTestPluginBody(`{}`, log), 0o755)
+
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"foo"}))
+		assert.Equal(t, "run \n", pt.stdout.String())
+		assert.NoFileExists(t, log)
+	})
+
+	t.Run("we do not describe plugins to search an alias", func(t *testing.T) {
+		pt := newPluginTest(t)
+		log := filepath.Join(t.TempDir(), "log")
+		writeTestPlugin(t, pt.pluginDir, "example-foo", describingTestPluginBody(`{"aliases":["f"]}`, log), 0o755)
+
+		err := pt.disp.Main(context.Background(), []string{"f"})
+		assert.ErrorIs(t, err, ErrCommandNotFound)
+		assert.NoFileExists(t, log)
+	})
+
+	t.Run("we persist the descriptions across dispatchers", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		newPluginTestWithCache := func() *pluginTest {
+			pt := newPluginTest(t)
+			lookupEnv := pt.disp.LookupEnv
+			pt.disp.LookupEnv = func(key string) (string, bool) {
+				if key == "XDG_CACHE_HOME" {
+					return cacheDir, true
+				}
+				return lookupEnv(key)
+			}
+			return pt
+		}
+		log := filepath.Join(t.TempDir(), "log")
+
+		first := newPluginTestWithCache()
+		pluginDir := first.pluginDir
+		writeTestPlugin(t, pluginDir, "example-foo", describingTestPluginBody(`{"aliases":["f"]}`, log), 0o755)
+		require.NoError(t, first.disp.Main(context.Background(), []string{"--help"}))
+		assert.FileExists(t, filepath.Join(cacheDir, "example", "plugins.json"))
+
+		// a new dispatcher resolves the alias using the cache
+		second := newPluginTestWithCache()
+		second.disp.EnablePlugins(pluginDir)
+		require.NoError(t, second.disp.Main(context.Background(), []string{"f", "x"}))
+		assert.Equal(t, "run x\n", second.stdout.String())
+		data, err := os.ReadFile(log)
+		require.NoError(t, err)
+		assert.Equal(t, "describe\n", string(data))
+
+		// changing the plugin invalidates its cache entry
+		writeTestPlugin(t, pluginDir, "example-foo", describingTestPluginBody(`{"aliases":["g"]}`, log)+"\n", 0o755)
+		third := newPluginTestWithCache()
+		third.disp.EnablePlugins(pluginDir)
+		assert.ErrorIs(t, third.disp.Main(context.Background(), []string{"f"}), ErrCommandNotFound)
+		require.NoError(t, third.disp.Main(context.Background(), []string{"--help"}))
+		data, err = os.ReadFile(log)
+		require.NoError(t, err)
+		assert.Equal(t, "describe\ndescribe\n", string(data))
+	})
+
+	t.Run("we describe the plugins in parallel", func(t *testing.T) {
+		pt := newPluginTest(t)
+		pt.disp.PluginDescribeTimeout = 5 * time.Second
+		for _, name := range []string{"example-foo", "example-bar", "example-baz"} {
+			writeTestPlugin(t, pt.pluginDir, name, `sleep 1; echo '{"description":["Slow."]}'`, 0o755)
+		}
+
+		start := time.Now()
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"--help"}))
+		assert.Contains(t, pt.stdout.String(), "Plugins\n\n    bar\n\n        Slow.\n")
+		assert.Less(t, time.Since(start), 2500*time.Millisecond)
+	})
+
+	t.Run("we limit the number of plugins described at once", func(t *testing.T) {
+		pt := newPluginTest(t)
+		running, log := t.TempDir(), filepath.Join(t.TempDir(), "log")
+		body := `touch '` + running + `'/$$; sleep 0.2; ls '` + running + `' | wc -l >> '` + log + `'; rm '` + running + `'/$$; echo '{}'`
+		for idx := range 3 * maxParallelPluginDescribes {
+			writeTestPlugin(t, pt.pluginDir, fmt.Sprintf("example-p%02d", idx), body, 0o755)
+		}
+
+		require.NoError(t, pt.disp.Main(context.Background(), []string{"--help"}))
+		assert.Contains(t, pt.stdout.String(), "    p00\n")
+		assert.Contains(t, pt.stdout.String(), fmt.Sprintf("    p%02d\n", 3*maxParallelPluginDescribes-1))
+		data, err := os.ReadFile(log)
+		require.NoError(t, err)
+		counts := strings.Fields(string(data))
+		assert.Len(t, counts, 3*maxParallelPluginDescribes)
+		for _, count := range counts {
+			value, err := strconv.Atoi(count)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, value, maxParallelPluginDescribes)
+		}
+	})
+
+	t.Run("we use an empty description on failure", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			body string
+		}{{
+			name: "invalid JSON",
+			body: "echo '{'",
+		}, {
+			name: "failure",
+			body: `echo '{"description":["Frobnicate things."]}'; exit 1`,
+		}, {
+			name: "timeout",
+			body: `sleep 10; echo '{"description":["Frobnicate things."]}'`,
+		}}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				pt := newPluginTest(t)
+				pt.disp.PluginDescribeTimeout = 100 * time.Millisecond
+				writeTestPlugin(t, pt.pluginDir, "example-foo", tc.body, 0o755)
+
+				start := time.Now()
+				require.NoError(t, pt.disp.Main(context.Background(), []string{"--help"}))
+				assert.Contains(t, pt.stdout.String(), "Plugins\n\n    foo\n\nHints\n")
+				assert.Less(t, time.Since(start), 5*time.Second)
+			})
+		}
+	})
+}
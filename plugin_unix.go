@@ -0,0 +1,23 @@
+//go:build unix
+
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import "io/fs"
+
+// pluginFileNames returns the candidate file names of the plugin with the given name.
+func pluginFileNames(name string) []string {
+	return []string{name}
+}
+
+// pluginBaseName returns the plugin name corresponding to the given file
+// name and whether the file name could be a plugin executable.
+func pluginBaseName(fileName string) (string, bool) {
+	return fileName, true
+}
+
+// isPluginExecutable returns whether the file is an executable regular file.
+func isPluginExecutable(info fs.FileInfo) bool {
+	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// pluginExtensions contains the extensions of plugin executables.
+var pluginExtensions = []string{".exe", ".com", ".bat", ".cmd"}
+
+// pluginFileNames returns the candidate file names of the plugin with the given name.
+func pluginFileNames(name string) []string {
+	var names []string
+	for _, ext := range pluginExtensions {
+		names = append(names, name+ext)
+	}
+	return names
+}
+
+// pluginBaseName returns the plugin name corresponding to the given file
+// name and whether the file name could be a plugin executable.
+func pluginBaseName(fileName string) (string, bool) {
+	ext := filepath.Ext(fileName)
+	for _, candidate := range pluginExtensions {
+		if strings.EqualFold(ext, candidate) {
+			return strings.TrimSuffix(fileName, ext), true
+		}
+	}
+	return "", false
+}
+
+// isPluginExecutable returns whether the file is a regular file.
+func isPluginExecutable(info fs.FileInfo) bool {
+	return info.Mode().IsRegular()
+}
@@ -8,8 +8,12 @@ package vclip
 
 import (
 	"context"
+	"errors"
+	"io"
+	"os"
 	"os/signal"
 
+	"github.com/bassosimone/must"
 	"github.com/bassosimone/runtimex"
 )
 
@@ -22,17 +26,30 @@ type RootCommand struct {
 	// Set to the parameter passed to [NewRootCommand].
 	Command Command
 
-	// LogFatalOnError0 is the function to call when Command fails.
+	// Exit is the function to call when Command fails with an [ExitCoder].
+	//
+	// [NewRootCommand] initializes it to [os.Exit].
+	Exit func(status int)
+
+	// LogFatalOnError0 is the function to call when Command fails
+	// with an error that is not an [ExitCoder].
 	//
 	// [NewRootCommand] initializes it to [runtimex.LogFatalOnError0].
 	LogFatalOnError0 func(err error)
+
+	// Stderr is the [io.Writer] where to print errors implementing [ExitCoder].
+	//
+	// [NewRootCommand] initializes it to [os.Stderr].
+	Stderr io.Writer
 }
 
 // NewRootCommand creates and returns a new [*RootCommand] instance.
 func NewRootCommand(cmd Command) *RootCommand {
 	return &RootCommand{
 		Command:          cmd,
+		Exit:             os.Exit,
 		LogFatalOnError0: runtimex.LogFatalOnError0,
+		Stderr:           os.Stderr,
 	}
 }
 
@@ -41,12 +58,27 @@ func NewRootCommand(cmd Command) *RootCommand {
 // 1. we wrap the [context.Context] using [signal.NotifyContext] so that
 // interruptions such as `^C` interrupt the command execution.
 //
-// 2. we use the LogFatalOnError0 field to ensure that the error
+// 2. when the command fails with an [ExitCoder], we print the error on the Stderr,
+// unless it is a [QuietExitCoder] asking not to, and we call Exit with its exit code.
+//
+// 3. otherwise, we use the LogFatalOnError0 field to ensure that the error
 // returned by the command is logged and leads to exiting.
 //
 // The args MUST NOT contain the program name (i.e. use `os.Args[1:]`).
+//
+// This method panics on I/O error.
 func (cmd *RootCommand) Main(ctx context.Context, args []string) {
 	ctx, cancel := signal.NotifyContext(ctx, interruptSignals...)
 	defer cancel()
-	cmd.LogFatalOnError0(cmd.Command.Main(ctx, args))
+	err := cmd.Command.Main(ctx, args)
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		code, printable := exitStatus(err)
+		if printable {
+			must.Fprintf(cmd.Stderr, "%s\n", err.Error())
+		}
+		cmd.Exit(code)
+		return
+	}
+	cmd.LogFatalOnError0(err)
 }
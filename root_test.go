@@ -3,8 +3,10 @@
 package vclip_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/bassosimone/vclip"
@@ -39,3 +41,44 @@ func TestRootCommandMain_Failure(t *testing.T) {
 	root.Main(ctx, []string{})
 	require.Equal(t, expectedErr, gotErr)
 }
+
+func TestRootCommandMain_ExitCoder(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		stderr string
+	}{{
+		name:   "exit error",
+		err:    &vclip.ExitError{Code: 3, Err: errors.New("not found on server")},
+		status: 3,
+		stderr: "not found on server\n",
+	}, {
+		name:   "quiet exit error",
+		err:    fmt.Errorf("wrapped: %w", &vclip.ExitError{Code: 4, Quiet: true}),
+		status: 4,
+		stderr: "",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			command := vclip.CommandFunc(func(ctx context.Context, args []string) error {
+				return tc.err
+			})
+			root := vclip.NewRootCommand(command)
+			var (
+				status int
+				stderr bytes.Buffer
+			)
+			root.Exit = func(code int) {
+				status = code
+			}
+			root.LogFatalOnError0 = func(err error) {
+				require.NoError(t, err)
+			}
+			root.Stderr = &stderr
+			root.Main(context.Background(), []string{})
+			require.Equal(t, tc.status, status)
+			require.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of suggestions we emit.
+const maxSuggestions = 3
+
+// suggestCommands returns the names and aliases of the non-hidden commands, and the
+// shortcuts, that are close enough to the given name to be worth suggesting.
+//
+// We sort suggestions by increasing edit distance and then alphabetically.
+func (c *DispatcherCommand) suggestCommands(name string) []string {
+	type suggestion struct {
+		word     string
+		distance int
+	}
+	threshold := max(1, len([]rune(name))/3)
+	var suggestions []suggestion
+	for _, cmdName := range slices.Sorted(maps.Keys(c.Commands)) {
+		if c.Commands[cmdName].Hidden {
+			continue
+		}
+		for _, word := range append([]string{cmdName}, c.CommandNameToAliases[cmdName]...) {
+			if distance := editDistance(name, word); distance <= threshold {
+				suggestions = append(suggestions, suggestion{word: word, distance: distance})
+			}
+		}
+	}
+	for _, word := range c.shortcutNames() {
+		if distance := editDistance(name, word); distance <= threshold {
+			suggestions = append(suggestions, suggestion{word: word, distance: distance})
+		}
+	}
+	slices.SortStableFunc(suggestions, func(a, b suggestion) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.word, b.word)
+	})
+	var words []string
+	for idx := 0; idx < len(suggestions) && idx < maxSuggestions; idx++ {
+		words = append(words, suggestions[idx].word)
+	}
+	return words
+}
+
+// formatSuggestions formats the suggestions returned by suggestCommands
+// so that they can be appended to an error message.
+//
+// The return value is empty when there are no suggestions.
+func formatSuggestions(suggestions []string) string {
+	if len(suggestions) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %s?)", quoteWords(suggestions))
+}
+
+// quoteWords quotes each word and joins them (e.g., "`a', `b' or `c'").
+func quoteWords(words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		quoted = append(quoted, fmt.Sprintf("`%s'", word))
+	}
+	if len(quoted) <= 1 {
+		return strings.Join(quoted, "")
+	}
+	last := len(quoted) - 1
+	return fmt.Sprintf("%s or %s", strings.Join(quoted[:last], ", "), quoted[last])
+}
+
+// editDistance returns the optimal string alignment distance between a and b, that
+// is, the Levenshtein distance also counting adjacent transpositions as one edit.
+//
+// We count transpositions because swapping two letters is the most common typo.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b   string
+		expect int
+	}{
+		{"", "", 0},
+		{"curl", "curl", 0},
+		{"crul", "curl", 1},
+		{"cur", "curl", 1},
+		{"curls", "curl", 1},
+		{"cxrl", "curl", 1},
+		{"dig", "curl", 4},
+		{"", "dig", 3},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expect, editDistance(tc.a, tc.b), "%s -> %s", tc.a, tc.b)
+		assert.Equal(t, tc.expect, editDistance(tc.b, tc.a), "%s -> %s", tc.b, tc.a)
+	}
+}
+
+func TestDispatcherCommandSuggestCommands(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("curl", &testCommand{})
+	disp.AddCommand("dig", &testCommand{})
+	disp.MustAddCommandAlias("dig", "dog")
+
+	assert.Equal(t, []string{"curl"}, disp.suggestCommands("crul"))
+	assert.Equal(t, []string{"dig", "dog"}, disp.suggestCommands("dug"))
+	assert.Equal(t, []string{"help"}, disp.suggestCommands("hlep"))
+	assert.Empty(t, disp.suggestCommands("nope"))
+}
+
+func TestFormatSuggestions(t *testing.T) {
+	assert.Equal(t, "", formatSuggestions(nil))
+	assert.Equal(t, " (did you mean `curl'?)", formatSuggestions([]string{"curl"}))
+	assert.Equal(t, " (did you mean `dig' or `dog'?)", formatSuggestions([]string{"dig", "dog"}))
+	assert.Equal(t, " (did you mean `a', `b' or `c'?)", formatSuggestions([]string{"a", "b", "c"}))
+}
+
+func TestDispatcherCommandMainCommandNotFoundSuggestions(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("curl", &testCommand{})
+
+	err := disp.Main(context.Background(), []string{"crul"})
+	require.ErrorIs(t, err, ErrCommandNotFound)
+	assert.Equal(t, "example: command not found: crul (did you mean `curl'?)", err.Error())
+
+	err = disp.Main(context.Background(), []string{"help", "crul"})
+	require.ErrorIs(t, err, ErrCommandNotFound)
+	assert.Equal(t, "example: command not found: crul (did you mean `curl'?)", err.Error())
+}
@@ -45,7 +45,11 @@ func (up *DefaultUsagePrinter) PrintHelp(c *DispatcherCommand, w io.Writer) {
 	must.Fprintf(w, "\n")
 	must.Fprintf(w, "Usage\n")
 	must.Fprintf(w, "\n")
-	must.Fprintf(w, "    %s <command> [args...]\n", c.Name)
+	if len(c.GlobalFlags) > 0 {
+		must.Fprintf(w, "    %s [options] <command> [args...]\n", c.FullName())
+	} else {
+		must.Fprintf(w, "    %s <command> [args...]\n", c.FullName())
+	}
 	must.Fprintf(w, "\n")
 
 	// ## Description
@@ -57,16 +61,59 @@ func (up *DefaultUsagePrinter) PrintHelp(c *DispatcherCommand, w io.Writer) {
 		must.Fprintf(w, "\n")
 	}
 
+	// ## Global options
+	if len(c.GlobalFlags) > 0 {
+		must.Fprintf(w, "Global options\n")
+		for _, flag := range c.GlobalFlags {
+			up.printGlobalFlag(w, &flag)
+		}
+		must.Fprintf(w, "\n")
+	}
+
 	// ## Commands
-	must.Fprintf(w, "Commands\n")
-	for _, name := range slices.Sorted(maps.Keys(c.Commands)) {
+	for idx, section := range c.commandSections() {
+		if idx > 0 {
+			must.Fprintf(w, "\n")
+		}
+		must.Fprintf(w, "%s\n", section.title)
+		for _, name := range section.names {
+			up.printCommand(c, w, name)
+		}
+	}
+
+	// ## Shortcuts
+	if names := c.shortcutNames(); len(names) > 0 {
+		must.Fprintf(w, "\n")
+		must.Fprintf(w, "Shortcuts\n")
+		for _, name := range names {
+			must.Fprintf(w, "\n")
+			must.Fprintf(w, "    %s\n", name)
+			up.div2(w, fmt.Sprintf("Shortcut for `%s'.", joinShellWords(c.CommandShortcuts[name])))
+		}
+	}
+
+	// ## User aliases
+	if names := c.userAliasNames(); len(names) > 0 {
 		must.Fprintf(w, "\n")
-		aliases := slices.Clone(c.CommandNameToAliases[name])
-		aliases = append(aliases, name)
-		must.Fprintf(w, "    %s\n", strings.Join(aliases, ", "))
-		command := c.Commands[name]
-		for _, paragraph := range command.Descr {
-			up.div2(w, paragraph)
+		must.Fprintf(w, "User aliases\n")
+		for _, name := range names {
+			must.Fprintf(w, "\n")
+			must.Fprintf(w, "    %s\n", name)
+			up.div2(w, fmt.Sprintf("Alias for `%s'.", joinShellWords(c.UserAliases[name])))
+		}
+	}
+
+	// ## Environment
+	envNames, envVars := c.declaredEnvVars()
+	if len(envVars) > 0 {
+		must.Fprintf(w, "\n")
+		must.Fprintf(w, "Environment\n")
+		for idx, envVar := range envVars {
+			must.Fprintf(w, "\n")
+			must.Fprintf(w, "    %s\n", envNames[idx])
+			for _, paragraph := range envVar.Descr {
+				up.div2(w, paragraph)
+			}
 		}
 	}
 
@@ -74,10 +121,21 @@ func (up *DefaultUsagePrinter) PrintHelp(c *DispatcherCommand, w io.Writer) {
 	must.Fprintf(w, "\n")
 	must.Fprintf(w, "Hints\n")
 	paragraphs := []string{
-		fmt.Sprintf("Use `%s <command> --help' to get command-specific help.", c.Name),
+		fmt.Sprintf("Use `%s <command> --help' to get command-specific help.", c.FullName()),
 		"Append `--help' or `-h' to any command line failing with usage errors to hide the " +
 			"error and obtain contextual help.",
 	}
+	if len(c.GlobalFlags) > 0 {
+		paragraphs = append(paragraphs, "Global options must appear before the command name.")
+	}
+	if c.pluginsEnabled {
+		paragraphs = append(paragraphs, fmt.Sprintf("Plugins are executables named `%s<command>' "+
+			"found in the plugin directories or in the PATH.", c.pluginPrefix()))
+	}
+	if len(envVars) > 0 {
+		paragraphs = append(paragraphs, "The environment variables of a command override "+
+			"the ones with the same name of its parent commands.")
+	}
 	for _, paragraph := range paragraphs {
 		up.div1(w, paragraph)
 	}
@@ -85,6 +143,102 @@ func (up *DefaultUsagePrinter) PrintHelp(c *DispatcherCommand, w io.Writer) {
 	must.Fprintf(w, "\n")
 }
 
+// commandSection is a titled list of command names.
+type commandSection struct {
+	// title is the section title.
+	title string
+
+	// names contains the command names.
+	names []string
+}
+
+// commandSections returns the sections listing the commands that are not hidden.
+//
+// We emit a section for each group in CommandGroups order, followed by a section for
+// the groups not listed in CommandGroups, if any, sorted alphabetically. Then, we emit
+// the "Commands" section containing the ungrouped commands, renamed "Other commands"
+// when there are groups. Then, we emit the "Plugins" section listing the non-hidden
+// plugins, if any. Last, we emit the "Deprecated" section, if needed.
+func (c *DispatcherCommand) commandSections() []commandSection {
+	var (
+		deprecated []string
+		grouped    = map[string][]string{}
+		ungrouped  []string
+	)
+	for _, name := range c.SortedCommandNames() {
+		switch command := c.Commands[name]; {
+		case command.Hidden:
+			// nothing
+		case command.Deprecated != nil:
+			deprecated = append(deprecated, name)
+		case command.Group != "":
+			grouped[command.Group] = append(grouped[command.Group], name)
+		default:
+			ungrouped = append(ungrouped, name)
+		}
+	}
+
+	var sections []commandSection
+	for _, group := range c.CommandGroups {
+		if names := grouped[group]; len(names) > 0 {
+			sections = append(sections, commandSection{title: group, names: names})
+			delete(grouped, group)
+		}
+	}
+	for _, group := range slices.Sorted(maps.Keys(grouped)) {
+		sections = append(sections, commandSection{title: group, names: grouped[group]})
+	}
+
+	switch {
+	case len(sections) <= 0:
+		sections = append(sections, commandSection{title: "Commands", names: ungrouped})
+	case len(ungrouped) > 0:
+		sections = append(sections, commandSection{title: "Other commands", names: ungrouped})
+	}
+
+	var plugins []string
+	for _, plugin := range c.discoverPlugins() {
+		if !plugin.command.Hidden {
+			plugins = append(plugins, plugin.name)
+		}
+	}
+	if len(plugins) > 0 {
+		sections = append(sections, commandSection{title: "Plugins", names: plugins})
+	}
+
+	if len(deprecated) > 0 {
+		sections = append(sections, commandSection{title: "Deprecated", names: deprecated})
+	}
+	return sections
+}
+
+// printCommand prints the aliases and the name of a command followed by its description.
+func (up *DefaultUsagePrinter) printCommand(c *DispatcherCommand, w io.Writer, name string) {
+	must.Fprintf(w, "\n")
+	command, aliases := c.childCommand(name)
+	aliases = append(slices.Clone(aliases), name)
+	must.Fprintf(w, "    %s\n", strings.Join(aliases, ", "))
+	for _, paragraph := range command.Descr {
+		up.div2(w, paragraph)
+	}
+	if command.Deprecated != nil {
+		up.div2(w, fmt.Sprintf("This command is deprecated%s.", command.Deprecated.details()))
+	}
+}
+
+// printGlobalFlag prints the names of a global flag followed by its description.
+func (up *DefaultUsagePrinter) printGlobalFlag(w io.Writer, flag *GlobalFlag) {
+	must.Fprintf(w, "\n")
+	if flag.ArgName != "" {
+		must.Fprintf(w, "    %s %s\n", flag.names(), flag.ArgName)
+	} else {
+		must.Fprintf(w, "    %s\n", flag.names())
+	}
+	for _, paragraph := range flag.Descr {
+		up.div2(w, paragraph)
+	}
+}
+
 // div1 prints a paragraph at 4-space indent level. If the paragraph starts
 // with 4 spaces, it is emitted verbatim (to allow preformatted blocks).
 func (up *DefaultUsagePrinter) div1(w io.Writer, entry string) {
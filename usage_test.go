@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherCommandCommandSections(t *testing.T) {
+	t.Run("without groups", func(t *testing.T) {
+		disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+		disp.AddCommand("curl", &testCommand{})
+
+		assert.Equal(t, []commandSection{
+			{title: "Commands", names: []string{"curl", "help"}},
+		}, disp.commandSections())
+	})
+
+	t.Run("with groups", func(t *testing.T) {
+		disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+		disp.AddCommand("curl", &testCommand{})
+		disp.AddCommand("dig", &testCommand{})
+		disp.AddCommand("ping", &testCommand{})
+		disp.AddCommand("debug", &testCommand{})
+		disp.AddCommand("fetch", &testCommand{})
+		disp.AddCommandGroup("Network")
+		disp.AddCommandGroup("Diagnostics")
+		disp.AddCommandGroup("Empty")
+		disp.AddCommandGroup("Network")
+		disp.MustSetCommandGroup("ping", "Diagnostics")
+		disp.MustSetCommandGroup("dig", "Network")
+		disp.MustSetCommandGroup("curl", "Network")
+		disp.MustSetCommandGroup("debug", "Diagnostics")
+		disp.MustHideCommand("debug")
+		disp.MustSetCommandGroup("fetch", "Network")
+		disp.MustDeprecateCommand("fetch", "curl", "")
+
+		// set an undeclared group directly
+		help := disp.Commands["help"]
+		help.Group = "Builtin"
+		disp.Commands["help"] = help
+
+		assert.Equal(t, []string{"Network", "Diagnostics", "Empty"}, disp.CommandGroups)
+		assert.Equal(t, []commandSection{
+			{title: "Network", names: []string{"curl", "dig"}},
+			{title: "Diagnostics", names: []string{"ping"}},
+			{title: "Builtin", names: []string{"help"}},
+			{title: "Deprecated", names: []string{"fetch"}},
+		}, disp.commandSections())
+	})
+}
+
+func TestDispatcherCommandMustSetCommandGroupPanics(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.AddCommand("curl", &testCommand{})
+	disp.AddCommandGroup("Network")
+
+	assert.Panics(t, func() {
+		disp.MustSetCommandGroup("nope", "Network")
+	})
+	assert.Panics(t, func() {
+		disp.MustSetCommandGroup("curl", "Nope")
+	})
+}
@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// userAliasesConfigKey is the key of the configuration file section containing the user aliases.
+const userAliasesConfigKey = "aliases"
+
+// ErrUserAliasLoop indicates that expanding a user alias leads to the same user alias.
+var ErrUserAliasLoop = errors.New("user alias loop")
+
+// loadUserAliases adds to UserAliases the aliases contained in the `aliases` key of
+// the given [*ConfigSection]. The value of each alias is either a string, which we split
+// into words using shell-like quoting, or a list of strings. For example:
+//
+//	{
+//	  "aliases": {
+//	    "dns": "dig +short @1.1.1.1",
+//	    "get": ["curl", "-fsSL"]
+//	  }
+//	}
+func (c *DispatcherCommand) loadUserAliases(section *ConfigSection) error {
+	value, found := section.Lookup(userAliasesConfigKey)
+	if !found {
+		return nil
+	}
+	aliases, ok := value.(map[string]any)
+	if !ok {
+		return c.newUserAliasesError(section, "`%s' must be an object", userAliasesConfigKey)
+	}
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		var (
+			args []string
+			err  error
+		)
+		switch value := aliases[name].(type) {
+		case string:
+			args, err = splitShellWords(value)
+		case []any:
+			for _, arg := range value {
+				str, ok := arg.(string)
+				if !ok {
+					err = errors.New("expected a string or a list of strings")
+					break
+				}
+				args = append(args, str)
+			}
+		default:
+			err = errors.New("expected a string or a list of strings")
+		}
+		switch {
+		case err != nil:
+			// nothing
+		case !isValidPluginName(name):
+			err = errors.New("invalid alias name")
+		case len(args) <= 0:
+			err = errors.New("empty expansion")
+		}
+		if err != nil {
+			return c.newUserAliasesError(section, "invalid alias `%s': %s", name, err.Error())
+		}
+		c.UserAliases[name] = args
+	}
+	return nil
+}
+
+// newUserAliasesError returns an error occurred when loading the user aliases.
+func (c *DispatcherCommand) newUserAliasesError(section *ConfigSection, format string, v ...any) error {
+	msg := fmt.Sprintf(format, v...)
+	if section.FileName() != "" {
+		msg = fmt.Sprintf("%s: %s", section.FileName(), msg)
+	}
+	return fmt.Errorf("%s: %s", c.FullName(), msg)
+}
+
+// expandAliases replaces the command name at the beginning of args with the expansion
+// of the corresponding shortcut, if any, or repeatedly with the expansion of the
+// corresponding user alias, if any. User aliases never shadow registered commands,
+// aliases, and shortcuts, while they shadow plugins. When AllowPrefixMatching is
+// true, we also expand the only shortcut starting with the command name, unless
+// the name matches a plugin, consistently with lookupCommand.
+func (c *DispatcherCommand) expandAliases(args []string) ([]string, error) {
+	var seen []string
+	for len(args) > 0 {
+		if _, found := c.findCommand(args[0]); found {
+			break
+		}
+		if expansion, found := c.CommandShortcuts[args[0]]; found {
+			// shortcuts always expand to registered commands
+			return append(slices.Clone(expansion), args[1:]...), nil
+		}
+		expansion, found := c.UserAliases[args[0]]
+		if !found {
+			return c.expandShortcutPrefix(args), nil
+		}
+		if slices.Contains(seen, args[0]) {
+			return nil, fmt.Errorf("%s: %w: %s", c.FullName(), ErrUserAliasLoop, strings.Join(append(seen, args[0]), " -> "))
+		}
+		seen = append(seen, args[0])
+		args = append(slices.Clone(expansion), args[1:]...)
+	}
+	return args, nil
+}
+
+// expandShortcutPrefix implements prefix matching for expandAliases.
+func (c *DispatcherCommand) expandShortcutPrefix(args []string) []string {
+	if !c.AllowPrefixMatching || args[0] == "" {
+		return args
+	}
+	if _, _, found := c.findPlugin(args[0]); found {
+		return args
+	}
+	candidates := c.prefixCandidates(args[0])
+	if len(candidates) != 1 || c.CommandShortcuts[candidates[0]] == nil {
+		return args
+	}
+	return append(slices.Clone(c.CommandShortcuts[candidates[0]]), args[1:]...)
+}
+
+// userAliasNames returns the sorted names of the user aliases that do
+// not conflict with registered commands, aliases, and shortcuts.
+func (c *DispatcherCommand) userAliasNames() []string {
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(c.UserAliases)) {
+		if _, found := c.findCommand(name); !found && c.CommandShortcuts[name] == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// splitShellWords splits a string into words using a subset of the POSIX shell rules: words
+// are separated by blanks, single quotes preserve the literal value of the characters they
+// contain, double quotes do the same except for the backslash escaping `"' and `\', and,
+// outside of quotes, a backslash preserves the literal value of the next character.
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'' && r == '\'':
+			quote = 0
+		case quote == '\'':
+			word.WriteRune(r)
+		case quote == '"' && r == '"':
+			quote = 0
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	switch {
+	case escaped:
+		return nil, errors.New("trailing backslash")
+	case quote != 0:
+		return nil, errors.New("unterminated quote")
+	case inWord:
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// joinShellWords is the inverse of splitShellWords: it joins the given words
+// using blanks and quotes the words containing special characters.
+func joinShellWords(words []string) string {
+	var quoted []string
+	for _, word := range words {
+		if word == "" || strings.ContainsAny(word, " \t\n'\"\\$`|&;<>()*?[]#~!{}") {
+			word = shellQuote(word)
+		}
+		quoted = append(quoted, word)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveArgs returns a [Command] saving the arguments it runs with into args.
+func saveArgs(args *[]string) Command {
+	return CommandFunc(func(ctx context.Context, cmdArgs []string) error {
+		*args = cmdArgs
+		return nil
+	})
+}
+
+const testUserAliasesConfigJSON = `{
+  "aliases": {
+    "dns": "dig +short '@1.1.1.1'",
+    "quad9": ["dns", "@9.9.9.9"],
+    "version": "dig version",
+    "loop1": "loop2 x",
+    "loop2": "loop1 y"
+  },
+  "net": {
+    "aliases": {"get": "curl -fsSL"}
+  }
+}`
+
+func TestDispatcherCommandUserAliases(t *testing.T) {
+	name := writeTestConfig(t, filepath.Join(t.TempDir(), "config.json"), testUserAliasesConfigJSON)
+	var digArgs, curlArgs []string
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.Stdout = &bytes.Buffer{}
+	net.AddCommand("curl", saveArgs(&curlArgs), "Utility to transfer URLs.")
+
+	var stdout bytes.Buffer
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.LookupEnv = func(key string) (string, bool) {
+		return name, key == "EXAMPLE_CONFIG"
+	}
+	disp.Stdout = &stdout
+	disp.AddCommand("dig", saveArgs(&digArgs), "Utility to query DNS servers.")
+	disp.AddCommand("net", net, "Network commands.")
+	disp.AddVersionHandlers("0.1.0")
+	disp.EnableConfig()
+
+	t.Run("we prepend the expansion to the remaining args", func(t *testing.T) {
+		require.NoError(t, disp.Main(context.Background(), []string{"dns", "example.com"}))
+		assert.Equal(t, []string{"+short", "@1.1.1.1", "example.com"}, digArgs)
+	})
+
+	t.Run("an expansion may start with another alias", func(t *testing.T) {
+		require.NoError(t, disp.Main(context.Background(), []string{"quad9", "example.com"}))
+		assert.Equal(t, []string{"+short", "@1.1.1.1", "@9.9.9.9", "example.com"}, digArgs)
+	})
+
+	t.Run("aliases never shadow registered commands", func(t *testing.T) {
+		digArgs = nil
+		stdout.Reset()
+		require.NoError(t, disp.Main(context.Background(), []string{"version"}))
+		assert.Nil(t, digArgs)
+		assert.Equal(t, "0.1.0\n", stdout.String())
+	})
+
+	t.Run("we detect loops", func(t *testing.T) {
+		err := disp.Main(context.Background(), []string{"loop1"})
+		assert.ErrorIs(t, err, ErrUserAliasLoop)
+		assert.EqualError(t, err, "example: user alias loop: loop1 -> loop2 -> loop1")
+	})
+
+	t.Run("nested dispatchers use their own section", func(t *testing.T) {
+		require.NoError(t, disp.Main(context.Background(), []string{"net", "get", "https://example.com/"}))
+		assert.Equal(t, []string{"-fsSL", "https://example.com/"}, curlArgs)
+
+		err := disp.Main(context.Background(), []string{"get"})
+		assert.ErrorIs(t, err, ErrCommandNotFound)
+	})
+
+	t.Run("the usage lists the user aliases", func(t *testing.T) {
+		stdout.Reset()
+		require.NoError(t, disp.Main(context.Background(), []string{"--help"}))
+		assert.Contains(t, stdout.String(), "\nUser aliases\n\n    dns\n\n        Alias for `dig +short @1.1.1.1'.\n")
+		assert.Contains(t, stdout.String(), "\n    quad9\n\n        Alias for `dns @9.9.9.9'.\n")
+		assert.NotContains(t, stdout.String(), "Alias for `dig version'.")
+	})
+
+	t.Run("the help subcommand resolves user aliases", func(t *testing.T) {
+		var stderr bytes.Buffer
+		disp.Stderr = &stderr
+		require.NoError(t, disp.Main(context.Background(), []string{"help", "quad9"}))
+		require.NoError(t, disp.Main(context.Background(), []string{"help", "net", "get"}))
+		assert.Empty(t, stderr.String())
+
+		stdout.Reset()
+		require.NoError(t, disp.Main(context.Background(), []string{"help", "--json", "net", "get"}))
+		assert.Contains(t, stdout.String(), `"name": "curl"`)
+	})
+}
+
+func TestDispatcherCommandUserAliasesErrors(t *testing.T) {
+	var (
+		digArgs []string
+		name    string
+	)
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.LookupEnv = func(key string) (string, bool) {
+		return name, key == "EXAMPLE_CONFIG"
+	}
+	disp.Stdout = &bytes.Buffer{}
+	disp.AddCommand("dig", saveArgs(&digArgs), "Utility to query DNS servers.")
+	disp.EnableConfig()
+
+	testCases := []struct {
+		name   string
+		config string
+		expect string
+	}{{
+		name:   "not an object",
+		config: `{"aliases": []}`,
+		expect: "`aliases' must be an object",
+	}, {
+		name:   "invalid type",
+		config: `{"aliases": {"dns": 1}}`,
+		expect: "invalid alias `dns': expected a string or a list of strings",
+	}, {
+		name:   "invalid list element",
+		config: `{"aliases": {"dns": ["dig", 1]}}`,
+		expect: "invalid alias `dns': expected a string or a list of strings",
+	}, {
+		name:   "unterminated quote",
+		config: `{"aliases": {"dns": "dig '@1.1.1.1"}}`,
+		expect: "invalid alias `dns': unterminated quote",
+	}, {
+		name:   "empty expansion",
+		config: `{"aliases": {"dns": " "}}`,
+		expect: "invalid alias `dns': empty expansion",
+	}, {
+		name:   "invalid name",
+		config: `{"aliases": {"-d": "dig"}}`,
+		expect: "invalid alias `-d': invalid alias name",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name = writeTestConfig(t, filepath.Join(t.TempDir(), "config.json"), tc.config)
+			err := disp.Main(context.Background(), []string{"dig"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "config.json: "+tc.expect)
+			assert.Nil(t, digArgs)
+
+			// a broken config does not prevent users from getting help
+			require.NoError(t, disp.Main(context.Background(), []string{"help"}))
+		})
+	}
+}
+
+func TestDispatcherCommandUserAliasesCompletion(t *testing.T) {
+	name := writeTestConfig(t, filepath.Join(t.TempDir(), "config.json"), testUserAliasesConfigJSON)
+	var curlArgs []string
+	net := NewDispatcherCommand("net", vflag.ContinueOnError)
+	net.AddCommand("curl", &testCompleterCommand{CompleterFunc: func(ctx context.Context, args []string) ([]Completion, error) {
+		curlArgs = args
+		return nil, nil
+	}}, "Utility to transfer URLs.")
+
+	var stdout bytes.Buffer
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.LookupEnv = func(key string) (string, bool) {
+		return name, key == "EXAMPLE_CONFIG"
+	}
+	disp.Stdout = &stdout
+	disp.AddCommand("dig", &testCommand{}, "Utility to query DNS servers.")
+	disp.AddCommand("net", net, "Network commands.")
+	disp.EnableConfig()
+
+	t.Run("we complete the user aliases", func(t *testing.T) {
+		stdout.Reset()
+		require.NoError(t, disp.Main(context.Background(), []string{"__complete", "d"}))
+		assert.Equal(t, "dig\tUtility to query DNS servers.\ndns\tAlias for `dig +short @1.1.1.1'.\n", stdout.String())
+	})
+
+	t.Run("we route the completion through the user aliases", func(t *testing.T) {
+		require.NoError(t, disp.Main(context.Background(), []string{"__complete", "net", "get", "https"}))
+		assert.Equal(t, []string{"-fsSL", "https"}, curlArgs)
+	})
+}
+
+func TestSplitShellWords(t *testing.T) {
+	testCases := []struct {
+		input  string
+		expect []string
+		err    string
+	}{
+		{input: "", expect: nil},
+		{input: "  dig   +short\t@1.1.1.1 ", expect: []string{"dig", "+short", "@1.1.1.1"}},
+		{input: `curl -H 'X-Foo: "bar"'`, expect: []string{"curl", "-H", `X-Foo: "bar"`}},
+		{input: `echo "a \"b\" \\ \n"`, expect: []string{"echo", `a "b" \ \n`}},
+		{input: `echo a\ b\'c`, expect: []string{"echo", "a b'c"}},
+		{input: `echo '' ""`, expect: []string{"echo", "", ""}},
+		{input: `echo x'y'"z"`, expect: []string{"echo", "xyz"}},
+		{input: `echo "x`, err: "unterminated quote"},
+		{input: `echo x\`, err: "trailing backslash"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			words, err := splitShellWords(tc.input)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, words)
+		})
+	}
+}
+
+func TestJoinShellWords(t *testing.T) {
+	words := []string{"curl", "-H", "X-Foo: bar", "", "it's"}
+	joined := joinShellWords(words)
+	assert.Equal(t, `curl -H 'X-Foo: bar' '' 'it'\''s'`, joined)
+	split, err := splitShellWords(joined)
+	require.NoError(t, err)
+	assert.Equal(t, words, split)
+}
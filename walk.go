@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"errors"
+	"slices"
+)
+
+// CommandNode is a node of the command tree visited by [Walk].
+type CommandNode struct {
+	// Aliases contains the aliases of the command within its parent.
+	Aliases []string
+
+	// Command is the command as registered within its parent.
+	//
+	// This field is the zero value for the root node.
+	Command DescribedCommand
+
+	// Dispatcher is not nil when the command is a [*DispatcherCommand].
+	Dispatcher *DispatcherCommand
+
+	// Hidden is true when the command or any of its ancestors is hidden.
+	Hidden bool
+
+	// Parent is the parent node or nil for the root node.
+	Parent *CommandNode
+
+	// Path contains the full command path (e.g., `example net curl`).
+	//
+	// For the root node, the path is the Path of the [*DispatcherCommand]
+	// passed to [Walk], which includes the names of its parents, if any.
+	Path []string
+
+	// Shortcuts maps the names of the shortcuts expanding to the command within its
+	// parent to the arguments following the command name (e.g., `ll` to `-l` when
+	// `ll` expands to `ls -l`). See [*DispatcherCommand.MustAddCommandShortcut].
+	Shortcuts map[string][]string
+}
+
+// Name returns the name of the command within its parent.
+func (n *CommandNode) Name() string {
+	return n.Path[len(n.Path)-1]
+}
+
+// Description returns the description paragraphs of the command. For a
+// [*DispatcherCommand] with a description, it returns its description,
+// otherwise the description used when registering the command.
+func (n *CommandNode) Description() []string {
+	if n.Dispatcher != nil && len(n.Dispatcher.Description) > 0 {
+		return n.Dispatcher.Description
+	}
+	return n.Command.Descr
+}
+
+// Summary returns the one-line summary of the command, which is the first
+// paragraph of the description used when registering the command or, if
+// missing, the first paragraph of the [*DispatcherCommand] description.
+func (n *CommandNode) Summary() string {
+	if summary := firstParagraph(n.Command.Descr); summary != "" {
+		return summary
+	}
+	return firstParagraph(n.Description())
+}
+
+// isBuiltin returns whether the command is one of the built-in commands of its
+// parent (e.g., `help`), which the documentation generators do not document.
+func (n *CommandNode) isBuiltin() bool {
+	return n.Parent != nil && n.Parent.Dispatcher.isBuiltinCommand(n.Name())
+}
+
+// SkipCommand is used as a return value from a [WalkFunc] to indicate
+// that the commands reachable from the current node should be skipped.
+var SkipCommand = errors.New("skip this command")
+
+// SkipAll is used as a return value from a [WalkFunc] to
+// indicate that all the remaining nodes should be skipped.
+var SkipAll = errors.New("skip everything and stop the walk")
+
+// WalkFunc is the type of the function called by [Walk] to visit each node.
+//
+// Returning [SkipCommand] skips the commands reachable from the current node,
+// returning [SkipAll] stops the walk, and returning any other non-nil error
+// stops the walk and causes [Walk] to return such an error.
+type WalkFunc func(node *CommandNode) error
+
+// Walk visits the given [*DispatcherCommand] and, in depth-first order, the
+// commands reachable from it, including nested [*DispatcherCommand] and
+// hidden commands. Each [*DispatcherCommand] visits its commands in the
+// order defined by its CommandOrder, followed by its plugins sorted by name.
+//
+// The return value is the error returned by fn or nil, when the walk
+// completed or fn returned [SkipCommand] or [SkipAll].
+func Walk(root *DispatcherCommand, fn WalkFunc) error {
+	err := walk(&CommandNode{Dispatcher: root, Path: root.fullPath()}, fn)
+	if errors.Is(err, SkipCommand) || errors.Is(err, SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walk implements [Walk].
+func walk(node *CommandNode, fn WalkFunc) error {
+	if err := fn(node); err != nil || node.Dispatcher == nil {
+		return err
+	}
+	for _, name := range node.Dispatcher.childNames() {
+		command, aliases := node.Dispatcher.childCommand(name)
+		child := &CommandNode{
+			Aliases:   append([]string{}, aliases...),
+			Command:   command,
+			Hidden:    node.Hidden || command.Hidden,
+			Parent:    node,
+			Path:      append(slices.Clone(node.Path), name),
+			Shortcuts: node.Dispatcher.childShortcuts(name),
+		}
+		child.Dispatcher, _ = command.Cmd.(*DispatcherCommand)
+		err := walk(child, fn)
+		if errors.Is(err, SkipCommand) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
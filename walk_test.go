@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package vclip
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bassosimone/vflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func walkPaths(t *testing.T, root *DispatcherCommand, fn WalkFunc) []string {
+	var paths []string
+	err := Walk(root, func(node *CommandNode) error {
+		paths = append(paths, strings.Join(node.Path, " "))
+		return fn(node)
+	})
+	require.NoError(t, err)
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	disp := newDocsTestDispatcher()
+	disp.MustHideCommand("net")
+
+	hidden := map[string]bool{}
+	paths := walkPaths(t, disp, func(node *CommandNode) error {
+		hidden[strings.Join(node.Path, " ")] = node.Hidden
+		return nil
+	})
+	assert.Equal(t, []string{
+		"example",
+		"example debug",
+		"example fetch",
+		"example help",
+		"example net",
+		"example net curl",
+		"example net help",
+	}, paths)
+	assert.Equal(t, map[string]bool{
+		"example":          false,
+		"example debug":    true,
+		"example fetch":    false,
+		"example help":     false,
+		"example net":      true,
+		"example net curl": true,
+		"example net help": true,
+	}, hidden)
+}
+
+func TestWalkCommandOrder(t *testing.T) {
+	disp := NewDispatcherCommand("example", vflag.ContinueOnError)
+	disp.CommandOrder = CommandOrderRegistration
+	disp.AddCommand("zeta", &testCommand{})
+	disp.AddCommand("alpha", &testCommand{})
+
+	paths := walkPaths(t, disp, func(node *CommandNode) error { return nil })
+	assert.Equal(t, []string{"example", "example zeta", "example alpha", "example help"}, paths)
+}
+
+func TestWalkSkipCommand(t *testing.T) {
+	paths := walkPaths(t, newDocsTestDispatcher(), func(node *CommandNode) error {
+		if node.Name() == "net" {
+			return SkipCommand
+		}
+		return nil
+	})
+	assert.Equal(t, []string{"example", "example debug", "example fetch", "example help", "example net"}, paths)
+}
+
+func TestWalkSkipCommandAtRoot(t *testing.T) {
+	paths := walkPaths(t, newDocsTestDispatcher(), func(node *CommandNode) error {
+		return SkipCommand
+	})
+	assert.Equal(t, []string{"example"}, paths)
+}
+
+func TestWalkSkipAll(t *testing.T) {
+	paths := walkPaths(t, newDocsTestDispatcher(), func(node *CommandNode) error {
+		if node.Name() == "curl" {
+			return SkipAll
+		}
+		return nil
+	})
+	assert.Equal(t, []string{"example", "example debug", "example fetch", "example help", "example net", "example net curl"}, paths)
+}
+
+func TestWalkError(t *testing.T) {
+	expect := errors.New("mocked error")
+	var count int
+	err := Walk(newDocsTestDispatcher(), func(node *CommandNode) error {
+		count++
+		if node.Name() == "curl" {
+			return expect
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, expect)
+	assert.Equal(t, 6, count)
+}
+
+func TestCommandNodeDescription(t *testing.T) {
+	nodes := map[string]*CommandNode{}
+	walkPaths(t, newDocsTestDispatcher(), func(node *CommandNode) error {
+		nodes[strings.Join(node.Path, " ")] = node
+		return nil
+	})
+
+	root := nodes["example"]
+	assert.Equal(t, "example", root.Name())
+	assert.Equal(t, "Dispatcher for network commands.", root.Summary())
+	assert.Equal(t, []string{"Dispatcher for network commands."}, root.Description())
+
+	net := nodes["example net"]
+	assert.Equal(t, "Network-related commands.", net.Summary())
+	assert.Equal(t, []string{"Network commands.", "    example net curl https://example.com/"}, net.Description())
+
+	curl := nodes["example net curl"]
+	assert.Equal(t, []string{"c"}, curl.Aliases)
+	assert.Equal(t, "Utility to transfer URLs.", curl.Summary())
+	assert.Len(t, curl.Description(), 3)
+}